@@ -307,6 +307,7 @@ async function uploadFile(file, userInfo, progressItemId) {
         AWS.config.update({
             accessKeyId: accessKeyId,
             secretAccessKey: secretAccessKey,
+            sessionToken: sessionToken,
             region: 'us-east-1'
         });
 
@@ -364,7 +365,9 @@ async function uploadFile(file, userInfo, progressItemId) {
                 contentType: file.type || 'application/octet-stream',
                 bucket: bucket,
                 key: key,
-                url: `${endpoint}/${bucket}/${key}`
+                url: `${endpoint}/${bucket}/${key}`,
+                // 已有相同内容的文件时复用已有存储，不再保存第二份
+                reuseExisting: true
             })
         });
 
@@ -376,6 +379,11 @@ async function uploadFile(file, userInfo, progressItemId) {
             throw new Error(saveData.msg || '保存文件信息失败');
         }
 
+        const duplicates = (saveData.data && saveData.data.duplicates) || [];
+        if (duplicates.length > 0) {
+            showMessage(`「${file.name}」与已有文件「${duplicates[0].fileName}」内容相同`, 'info');
+        }
+
         if (progressItemId) {
             updateProgress(progressItemId, 100, '完成');
         }
@@ -390,15 +398,20 @@ async function uploadFile(file, userInfo, progressItemId) {
     }
 }
 
-// 加载文件列表
-async function loadFileList() {
+// 文件列表下一页的游标，为空表示没有更多
+let fileListCursor = '';
+
+// 加载文件列表；append 为 true 时加载下一页并追加到列表末尾
+async function loadFileList(append = false) {
     const userInfo = checkLoginStatus();
     if (!userInfo) return;
 
     const { token } = userInfo;
+    if (append && !fileListCursor) return;
 
     try {
-        const response = await fetch(`${API_BASE_URL}/material/list`, {
+        const query = append ? `?cursor=${encodeURIComponent(fileListCursor)}` : '';
+        const response = await fetch(`${API_BASE_URL}/material/list${query}`, {
             headers: {
                 'Authorization': `Bearer ${token}`
             }
@@ -412,11 +425,15 @@ async function loadFileList() {
         if (handleApiResult(data)) return;
         const fileList = document.getElementById('fileList');
         const emptyState = document.getElementById('emptyState');
+        const loadMore = document.getElementById('loadMore');
+
+        fileListCursor = (data.data && data.data.nextCursor) || '';
+        if (loadMore) loadMore.style.display = fileListCursor ? 'block' : 'none';
 
         if (data.code === 0 && data.data && data.data.materials && data.data.materials.length > 0) {
             fileList.style.display = 'grid';
             emptyState.style.display = 'none';
-            fileList.innerHTML = data.data.materials.map(file => {
+            const html = data.data.materials.map(file => {
                 // 判断是否为图片或视频类型文件（这些文件有缩略图）
                 const isMedia = file.contentType && (file.contentType.startsWith('image/') || file.contentType.startsWith('video/'));
                 // 判断是否为视频类型，用于显示右上角三角标识
@@ -462,7 +479,12 @@ async function loadFileList() {
                     <div class="file-date">${file.createdAt || file.CreatedAt}</div>
                 </div>
             `}).join('');
-        } else {
+            if (append) {
+                fileList.insertAdjacentHTML('beforeend', html);
+            } else {
+                fileList.innerHTML = html;
+            }
+        } else if (!append) {
             fileList.style.display = 'none';
             emptyState.style.display = 'block';
             emptyState.innerHTML = `
@@ -501,6 +523,11 @@ async function searchFiles(q) {
         return await loadFileList();
     }
 
+    // 搜索结果不分页
+    fileListCursor = '';
+    const loadMore = document.getElementById('loadMore');
+    if (loadMore) loadMore.style.display = 'none';
+
     try {
         const url = new URL(`${API_BASE_URL}/material/search`, window.location.origin);
         url.searchParams.set('q', q);
@@ -1173,6 +1200,79 @@ async function handleLogout() {
     window.location.href = 'login.html';
 }
 
+// 缩略图处理状态推送，生成封面后直接替换卡片上的占位图
+let processingSource = null;
+let processingConnecting = false;
+const PROCESSING_RETRY_MS = 5000;
+
+// EventSource 无法设置请求头，每次连接前先用登录token换取一次性票据，URL 中不出现登录token
+async function fetchStreamTicket(token) {
+    const response = await fetch(`${API_BASE_URL}/material/processing/ticket`, {
+        method: 'POST',
+        headers: {
+            'Content-Type': 'application/json',
+            'Authorization': `Bearer ${token}`
+        }
+    });
+    const data = await response.json();
+    if (handleApiResult(data)) {
+        throw new Error('token expired');
+    }
+    if (data.code !== 0 || !data.data) {
+        throw new Error(data.msg || '获取推送票据失败');
+    }
+    return data.data.ticket;
+}
+
+async function watchProcessingStatus(token) {
+    if (processingSource || processingConnecting || !window.EventSource) return;
+
+    processingConnecting = true;
+    let ticket;
+    try {
+        ticket = await fetchStreamTicket(token);
+    } catch (error) {
+        console.error('连接处理状态推送失败:', error);
+        return;
+    } finally {
+        processingConnecting = false;
+    }
+
+    processingSource = new EventSource(`${API_BASE_URL}/material/processing/stream?access_token=${encodeURIComponent(ticket)}`);
+    processingSource.addEventListener('status', (event) => {
+        let status;
+        try {
+            status = JSON.parse(event.data);
+        } catch (e) {
+            return;
+        }
+
+        const checkbox = document.querySelector(`.file-checkbox[data-id="${status.materialId}"]`);
+        const cover = checkbox ? checkbox.parentElement.querySelector('.file-cover') : null;
+        if (!cover) return;
+
+        if (status.state === 'done' && status.coverPreviewUrl) {
+            cover.textContent = '';
+            cover.style.backgroundColor = '';
+            cover.style.backgroundImage = `url('${status.coverPreviewUrl}')`;
+            cover.title = '';
+        } else if (status.state === 'failed') {
+            cover.title = '封面生成失败: ' + (status.error || '');
+        }
+    });
+    // 票据只能使用一次，浏览器自动重连会被拒绝：出错时关闭连接，稍后换取新票据重新连接
+    processingSource.onerror = () => {
+        processingSource.close();
+        processingSource = null;
+        setTimeout(() => {
+            const userInfo = checkLoginStatus();
+            if (userInfo) {
+                watchProcessingStatus(userInfo.token);
+            }
+        }, PROCESSING_RETRY_MS);
+    };
+}
+
 // 初始化页面
 // document.addEventListener('DOMContentLoaded', async function() {
 //     // 检查登录状态
@@ -0,0 +1,47 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Prefix API Key 的固定前缀，便于与JWT区分及密钥扫描
+const Prefix = "gdk_"
+
+// 可授予的权限范围
+const (
+	ScopeMaterialRead  = "material:read"
+	ScopeMaterialWrite = "material:write"
+	ScopeKBChat        = "kb:chat"
+)
+
+// IsValidScope 判断是否为支持的权限范围
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeMaterialRead, ScopeMaterialWrite, ScopeKBChat:
+		return true
+	}
+	return false
+}
+
+// Generate 生成新的API Key，返回明文（仅展示一次）、展示用前缀和存储用哈希
+func Generate() (key, display, hash string) {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	key = Prefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, key[:len(Prefix)+8], Hash(key)
+}
+
+// Hash 计算API Key的哈希，服务端只保存哈希
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAPIKey 判断Bearer凭证是否为API Key
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, Prefix)
+}
@@ -0,0 +1,114 @@
+package esx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"godir/internal/common/svc"
+	"godir/internal/model"
+)
+
+// MaterialIndex 素材索引名
+const MaterialIndex = "godir_material"
+
+// Enabled 是否配置了ES；未配置时 svc.ES() 返回的是空客户端，不能直接调用
+func Enabled() bool {
+	return len(svc.Cfg().ES.Addresses) > 0
+}
+
+// MaterialDoc 构造素材索引文档，tagIDs 用于按标签过滤搜索结果
+func MaterialDoc(m *model.GodirMaterial, tagIDs []uint) map[string]interface{} {
+	if tagIDs == nil {
+		tagIDs = []uint{}
+	}
+	return map[string]interface{}{
+		"id":                  m.ID,
+		"user_id":             m.UserID,
+		"file_name":           m.FileName,
+		"file_size":           m.FileSize,
+		"content_type":        m.ContentType,
+		"oss_bucket":          m.OssBucket,
+		"oss_file_path":       m.OssFilePath,
+		"cover_oss_file_path": m.CoverOssFilePath,
+		"created_at":          m.CreatedAt,
+		"tag_ids":             tagIDs,
+	}
+}
+
+// IndexMaterial 写入（覆盖）素材文档
+func IndexMaterial(ctx context.Context, m *model.GodirMaterial) error {
+	if !Enabled() {
+		return nil
+	}
+
+	var tagIDs []uint
+	if err := svc.DB().WithContext(ctx).Model(&model.GodirMaterialTag{}).
+		Where("material_id = ?", m.ID).
+		Pluck("tag_id", &tagIDs).Error; err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(MaterialDoc(m, tagIDs))
+	if err != nil {
+		return err
+	}
+
+	es := svc.ES()
+	resp, err := es.Index(
+		MaterialIndex,
+		bytes.NewReader(b),
+		es.Index.WithDocumentID(fmt.Sprintf("%d", m.ID)),
+		es.Index.WithContext(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.IsError() {
+		return fmt.Errorf("ES索引失败: %s", resp.String())
+	}
+	return nil
+}
+
+// ReindexMaterials 按ID重新写入素材文档（如标签变更后），返回第一个错误但会尝试全部素材
+func ReindexMaterials(ctx context.Context, ids []uint) error {
+	if !Enabled() || len(ids) == 0 {
+		return nil
+	}
+
+	var materials []model.GodirMaterial
+	if err := svc.DB().WithContext(ctx).Where("id IN ?", ids).Find(&materials).Error; err != nil {
+		return err
+	}
+
+	var firstErr error
+	for i := range materials {
+		if err := IndexMaterial(ctx, &materials[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// DeleteMaterial 删除素材文档，文档不存在视为成功
+func DeleteMaterial(ctx context.Context, id uint) error {
+	if !Enabled() {
+		return nil
+	}
+
+	es := svc.ES()
+	resp, err := es.Delete(MaterialIndex, fmt.Sprintf("%d", id), es.Delete.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.IsError() && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("ES删除失败: %s", resp.String())
+	}
+	return nil
+}
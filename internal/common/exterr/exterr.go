@@ -6,6 +6,23 @@ import (
 
 var Fail = New(-1, "fail")
 
+// 业务错误码
+const (
+	CodeInvalidToken int64 = 10000001 // token无效或已过期
+	CodeTokenRevoked int64 = 10000002 // token已被吊销
+
+	CodeInvalidRefreshToken int64 = 10000003 // 刷新token无效或已过期
+	CodeRefreshTokenReused  int64 = 10000004 // 刷新token被重复使用，整个家族已吊销
+	CodeForbidden           int64 = 10000005 // 当前角色无权限访问
+	CodeScopeDenied         int64 = 10000006 // API Key 缺少所需权限范围
+	CodeInvalidOTP          int64 = 10000007 // 两步验证码错误
+	CodeChallengeExpired    int64 = 10000008 // 两步验证会话已过期，需重新输入密码
+	CodeLoginLocked         int64 = 10000009 // 登录失败次数过多，账号或IP被临时锁定
+	CodeLoginThrottled      int64 = 10000010 // 登录失败后的退避等待期内
+	CodeQuotaExceeded       int64 = 10000011 // 存储空间不足
+	CodeSharePassword       int64 = 10000012 // 分享链接需要密码或密码错误
+)
+
 type exterr struct {
 	code int64
 	msg  string
@@ -3,14 +3,20 @@ package ginx
 import (
 	"net/http"
 	"strings"
+	"time"
 
+	"godir/internal/common/apikey"
 	"godir/internal/common/exterr"
 	"godir/internal/common/jwt"
+	"godir/internal/common/svc"
+	"godir/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware JWT认证中间件
+const sessionSeenKeyPrefix = "auth:session:seen:"
+
+// AuthMiddleware JWT认证中间件，同时接受 API Key（gdk_ 前缀）
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 从请求头获取token
@@ -31,14 +37,41 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := parts[1]
 
+		if apikey.IsAPIKey(tokenString) {
+			if err := authenticateAPIKey(c, tokenString); err != nil {
+				c.JSON(http.StatusOK, Fail(err))
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
 		// 解析token
 		claims, err := jwt.ParseToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusOK, Fail(exterr.Newf(10000001, "无效的token")))
+			c.JSON(http.StatusOK, Fail(exterr.Newf(exterr.CodeInvalidToken, "无效的token")))
+			c.Abort()
+			return
+		}
+
+		// 检查token是否已被吊销（退出登录/结束会话/退出所有设备）
+		revoked, err := jwt.IsRevoked(c.Request.Context(), claims)
+		if err != nil {
+			c.JSON(http.StatusOK, Fail(exterr.Newf(-1, "校验token状态失败")))
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusOK, Fail(exterr.Newf(exterr.CodeTokenRevoked, "token已失效，请重新登录")))
 			c.Abort()
 			return
 		}
 
+		if claims.Sid != "" {
+			touchSession(c, claims.Sid)
+		}
+
 		// 将用户信息存储到上下文
 		c.Set("userId", claims.UserID)
 		c.Set("userName", claims.Username)
@@ -48,3 +81,154 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// StreamAuth 长连接接口的认证，替代 AuthMiddleware：带 Authorization 头时按 AuthMiddleware 校验；
+// 否则只接受查询参数中的一次性票据（见 jwt.IssueStreamTicket），不接受登录token，避免长期有效的token出现在URL中
+func StreamAuth(param string) gin.HandlerFunc {
+	auth := AuthMiddleware()
+	return func(c *gin.Context) {
+		ticket := c.Query(param)
+		if ticket == "" || c.GetHeader("Authorization") != "" {
+			auth(c)
+			return
+		}
+
+		ctx := c.Request.Context()
+		claims, err := jwt.ConsumeStreamTicket(ctx, ticket)
+		if err != nil {
+			c.JSON(http.StatusOK, Fail(exterr.Newf(exterr.CodeInvalidToken, "无效的票据")))
+			c.Abort()
+			return
+		}
+
+		// 票据签发后登录token可能已被吊销
+		revoked, err := jwt.IsRevoked(ctx, claims)
+		if err != nil {
+			c.JSON(http.StatusOK, Fail(exterr.Newf(-1, "校验token状态失败")))
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusOK, Fail(exterr.Newf(exterr.CodeTokenRevoked, "token已失效，请重新登录")))
+			c.Abort()
+			return
+		}
+
+		c.Set("userId", claims.UserID)
+		c.Set("userName", claims.Username)
+		c.Set("userInfo", *claims)
+
+		c.Next()
+	}
+}
+
+// RequireRole 角色校验中间件，需放在 AuthMiddleware 之后使用
+func RequireRole(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		allowed[r] = true
+	}
+
+	return func(c *gin.Context) {
+		userInfo, exists := c.Get("userInfo")
+		if !exists {
+			c.JSON(http.StatusOK, Fail(exterr.Newf(-1, "未登录")))
+			c.Abort()
+			return
+		}
+
+		claims, ok := userInfo.(jwt.Claims)
+		if !ok || !allowed[claims.Policy] {
+			c.JSON(http.StatusOK, Fail(exterr.Newf(exterr.CodeForbidden, "无权限访问")))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// touchSession 更新会话最近活跃时间和IP，同一会话每分钟最多写一次库
+func touchSession(c *gin.Context, sessionID string) {
+	ctx := c.Request.Context()
+	ok, err := svc.Redis().SetNX(ctx, sessionSeenKeyPrefix+sessionID, 1, time.Minute).Result()
+	if err != nil || !ok {
+		return
+	}
+
+	svc.DB().WithContext(ctx).Model(&model.GodirSession{}).
+		Where("session_id = ?", sessionID).
+		UpdateColumns(map[string]any{"last_seen_at": time.Now(), "ip": c.ClientIP()})
+}
+
+// authenticateAPIKey 校验API Key并写入与JWT一致的用户上下文
+func authenticateAPIKey(c *gin.Context, key string) error {
+	db := svc.DB()
+
+	var k model.GodirAPIKey
+	if err := db.Where("key_hash = ?", apikey.Hash(key)).First(&k).Error; err != nil {
+		return exterr.Newf(exterr.CodeInvalidToken, "无效的API Key")
+	}
+
+	now := time.Now()
+	if k.ExpiresAt != nil && now.After(*k.ExpiresAt) {
+		return exterr.Newf(exterr.CodeInvalidToken, "API Key已过期")
+	}
+
+	var user model.GodirUser
+	if err := db.First(&user, k.UserID).Error; err != nil {
+		return exterr.Newf(exterr.CodeInvalidToken, "无效的API Key")
+	}
+
+	// 最近使用时间按分钟粒度更新，避免每个请求都写库
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) > time.Minute {
+		db.Model(&k).UpdateColumn("last_used_at", now)
+	}
+
+	c.Set("userId", user.ID)
+	c.Set("userName", user.Username)
+	c.Set("userInfo", jwt.Claims{
+		UserID:   user.ID,
+		Username: user.Username,
+		Policy:   user.Role,
+	})
+	c.Set("apiKeyId", k.ID)
+	c.Set("apiKeyScopes", k.ScopeList())
+	return nil
+}
+
+// RequireScope 权限范围校验中间件：通过API Key访问时必须具备指定范围，JWT登录不受限制
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if v, ok := c.Get("apiKeyScopes"); ok {
+			scopes, _ := v.([]string)
+			granted := false
+			for _, s := range scopes {
+				if s == scope {
+					granted = true
+					break
+				}
+			}
+			if !granted {
+				c.JSON(http.StatusOK, Fail(exterr.Newf(exterr.CodeScopeDenied, "API Key缺少权限: %s", scope)))
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RejectAPIKey 仅允许登录token访问，用于账号、密钥管理等敏感接口
+func RejectAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("apiKeyId"); ok {
+			c.JSON(http.StatusOK, Fail(exterr.Newf(exterr.CodeScopeDenied, "该接口不支持API Key访问")))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,18 @@
+package ginx
+
+import "sync"
+
+var (
+	streamsClosed    = make(chan struct{})
+	closeStreamsOnce sync.Once
+)
+
+// StreamsClosed 服务关闭时关闭的通道；SSE 等长连接接口需同时监听它，否则会一直拖住优雅退出
+func StreamsClosed() <-chan struct{} {
+	return streamsClosed
+}
+
+// CloseStreams 通知所有长连接退出，注册为 http.Server 的 OnShutdown 回调
+func CloseStreams() {
+	closeStreamsOnce.Do(func() { close(streamsClosed) })
+}
@@ -64,3 +64,32 @@ func WrapHandlerObj[T BaseHandlerInterface, X any, Y any](method func(T, *gin.Co
 		c.JSON(http.StatusOK, Success(resp))
 	}
 }
+
+// WrapStreamObj 用于自行写响应体的接口（如文件流）；method 在写出任何内容前返回错误时按普通接口返回JSON，
+// 已开始写出后出错只能中断连接，由 method 自行记录日志
+func WrapStreamObj[T BaseHandlerInterface, X any](method func(T, *gin.Context, *X) error) func(c *gin.Context) {
+	return func(c *gin.Context) {
+
+		var req X
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusOK, Fail(exterr.Newf(-1, "参数错误: %v", err)))
+			c.Abort()
+			return
+		}
+
+		var t T
+		obj := t.New().(T)
+		obj.SetLogger(logger.FromContext(c))
+		obj.SetCtx(c.Request.Context())
+		obj.SetRequestID(c.GetHeader("X-Request-ID"))
+		obj.SetDB(svc.DB())
+		obj.SetSvc(svc.Get())
+
+		if err := method(obj, c, &req); err != nil {
+			if !c.Writer.Written() {
+				c.JSON(http.StatusOK, Fail(err))
+			}
+			c.Abort()
+		}
+	}
+}
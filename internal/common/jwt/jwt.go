@@ -1,78 +1,129 @@
 package jwt
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
 
+	"godir/internal/common/svc"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
 var (
-	secretKey []byte
-	tokenExp  time.Duration
+	secretKey       []byte
+	tokenExp        time.Duration
+	refreshTokenExp time.Duration
 )
 
 type Claims struct {
 	UserID   uint   `json:"userId"`
 	Username string `json:"username"`
-	Policy   string `json:"policy"`
+	Policy   string `json:"policy"`        // 用户角色，见 model.Role*
+	Gen      int64  `json:"gen"`           // 签发时用户的token代数，用于"退出所有设备"
+	Sid      string `json:"sid,omitempty"` // 所属会话ID（即刷新token家族ID），用于按设备吊销
 	jwt.RegisteredClaims
 }
 
 // Init 初始化JWT配置
-func Init(secretKeyStr string, tokenExpStr string) error {
-	if secretKeyStr == "" {
-		return fmt.Errorf("JWT SecretKey不能为空")
+// 配置了 SigningKeyID 时使用非对称密钥签名，SecretKey 仅用于校验迁移期间的旧HS256 token
+func Init(cfg svc.JWTConfig) error {
+	secretKey = nil
+	if cfg.SecretKey != "" {
+		secretKey = []byte(cfg.SecretKey)
 	}
-	secretKey = []byte(secretKeyStr)
 
-	if tokenExpStr == "" {
-		tokenExp = 24 * time.Hour // 默认24小时
-	} else {
-		var err error
-		tokenExp, err = time.ParseDuration(tokenExpStr)
-		if err != nil {
-			return fmt.Errorf("JWT TokenExp格式错误: %w", err)
-		}
+	if err := loadKeys(cfg); err != nil {
+		return err
+	}
+
+	if secretKey == nil && signer == nil {
+		return fmt.Errorf("JWT SecretKey和SigningKeyID不能同时为空")
+	}
+
+	var err error
+	tokenExp, err = parseExp(cfg.TokenExp, 24*time.Hour) // 默认24小时
+	if err != nil {
+		return fmt.Errorf("JWT TokenExp格式错误: %w", err)
+	}
+
+	refreshTokenExp, err = parseExp(cfg.RefreshTokenExp, 30*24*time.Hour) // 默认30天
+	if err != nil {
+		return fmt.Errorf("JWT RefreshTokenExp格式错误: %w", err)
 	}
 
 	return nil
 }
 
-// GenerateToken 生成JWT token
-func GenerateToken(userID uint, username string) (string, error) {
-	if secretKey == nil {
-		return "", fmt.Errorf("JWT未初始化，请先调用jwt.Init")
+func parseExp(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// TokenExp 访问token有效期
+func TokenExp() time.Duration {
+	return tokenExp
+}
+
+// RefreshTokenExp 刷新token有效期
+func RefreshTokenExp() time.Duration {
+	return refreshTokenExp
+}
+
+// GenerateToken 生成JWT token，role 写入 Policy 声明，返回token及其jti
+func GenerateToken(userID uint, username string, role string, sessionID string) (string, string, error) {
+	if secretKey == nil && signer == nil {
+		return "", "", fmt.Errorf("JWT未初始化，请先调用jwt.Init")
+	}
+
+	gen, err := Generation(context.Background(), userID)
+	if err != nil {
+		return "", "", fmt.Errorf("获取token代数失败: %w", err)
 	}
 
 	claims := Claims{
 		UserID:   userID,
 		Username: username,
-		Policy:   "readwrite",
+		Policy:   role,
+		Gen:      gen,
+		Sid:      sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenID(),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenExp)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+	var signed string
+	if signer != nil {
+		token := jwt.NewWithClaims(signer.method, claims)
+		token.Header["kid"] = signer.kid
+		signed, err = token.SignedString(signer.private)
+	} else {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		signed, err = token.SignedString(secretKey)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	return signed, claims.ID, nil
 }
 
 // ParseToken 解析JWT token
 func ParseToken(tokenString string) (*Claims, error) {
-	if secretKey == nil {
+	if secretKey == nil && signer == nil {
 		return nil, fmt.Errorf("JWT未初始化，请先调用jwt.Init")
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return secretKey, nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keyFunc)
 
 	if err != nil {
 		return nil, err
@@ -84,3 +135,29 @@ func ParseToken(tokenString string) (*Claims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// newTokenID 生成唯一的 jti
+func newTokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// NewRefreshToken 生成随机刷新token，返回明文（仅下发给客户端）和用于存储的哈希
+func NewRefreshToken() (token string, hash string) {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token)
+}
+
+// HashRefreshToken 计算刷新token的哈希，服务端只保存哈希
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewFamilyID 生成刷新token家族ID，同一次登录轮换出的刷新token共享该ID
+func NewFamilyID() string {
+	return newTokenID()
+}
@@ -0,0 +1,219 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"godir/internal/common/svc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// key 一把非对称密钥；private 为空表示仅用于校验（已轮换下线的旧密钥）
+type key struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+var (
+	signer     *key            // 当前签名密钥
+	verifyKeys map[string]*key // 所有可用于校验的密钥，按kid索引
+)
+
+// JWK 公钥的JWK表示
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA modulus
+	E   string `json:"e,omitempty"`   // RSA exponent
+	Crv string `json:"crv,omitempty"` // OKP curve
+	X   string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKSet /.well-known/jwks.json 的响应
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// loadKeys 加载配置中的所有非对称密钥
+func loadKeys(cfg svc.JWTConfig) error {
+	signer = nil
+	verifyKeys = make(map[string]*key, len(cfg.Keys))
+
+	for _, kc := range cfg.Keys {
+		if kc.ID == "" {
+			return fmt.Errorf("JWT Keys中存在未设置ID的密钥")
+		}
+		if _, ok := verifyKeys[kc.ID]; ok {
+			return fmt.Errorf("JWT密钥ID重复: %s", kc.ID)
+		}
+
+		k, err := loadKey(kc)
+		if err != nil {
+			return fmt.Errorf("加载JWT密钥 %s 失败: %w", kc.ID, err)
+		}
+		verifyKeys[kc.ID] = k
+	}
+
+	if cfg.SigningKeyID != "" {
+		k, ok := verifyKeys[cfg.SigningKeyID]
+		if !ok {
+			return fmt.Errorf("JWT SigningKeyID %s 未在Keys中配置", cfg.SigningKeyID)
+		}
+		if k.private == nil {
+			return fmt.Errorf("JWT签名密钥 %s 缺少私钥", cfg.SigningKeyID)
+		}
+		signer = k
+	}
+
+	return nil
+}
+
+func loadKey(kc svc.JWTKeyConfig) (*key, error) {
+	k := &key{kid: kc.ID}
+
+	switch kc.Algorithm {
+	case "RS256":
+		k.method = jwt.SigningMethodRS256
+	case "EdDSA":
+		k.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("不支持的算法: %s", kc.Algorithm)
+	}
+
+	if kc.PrivateKeyFile != "" {
+		priv, err := readPrivateKey(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		k.private = priv
+		k.public = priv.Public()
+	}
+
+	if kc.PublicKeyFile != "" {
+		pub, err := readPublicKey(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		k.public = pub
+	}
+
+	if k.public == nil {
+		return nil, fmt.Errorf("PrivateKeyFile和PublicKeyFile不能同时为空")
+	}
+
+	// 校验密钥类型与算法是否匹配
+	switch k.public.(type) {
+	case *rsa.PublicKey:
+		if k.method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("RSA密钥只能用于RS256")
+		}
+	case ed25519.PublicKey:
+		if k.method != jwt.SigningMethodEdDSA {
+			return nil, fmt.Errorf("Ed25519密钥只能用于EdDSA")
+		}
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型 %T", k.public)
+	}
+
+	return k, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(bs)
+	if block == nil {
+		return nil, fmt.Errorf("%s 不是有效的PEM文件", path)
+	}
+	return block, nil
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if k, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if s, ok := k.(crypto.Signer); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("不支持的私钥类型 %T", k)
+	}
+	if k, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("无法解析私钥 %s", path)
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if k, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	if k, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return k, nil
+	}
+	return nil, fmt.Errorf("无法解析公钥 %s", path)
+}
+
+// keyFunc 根据token头部的kid选择校验密钥；无kid的token按旧的HS256密钥校验
+func keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || secretKey == nil {
+			return nil, errors.New("invalid signing method")
+		}
+		return secretKey, nil
+	}
+
+	k, ok := verifyKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+	return k.public, nil
+}
+
+// JWKS 返回所有校验公钥，供其他服务无需共享密钥即可校验token
+func JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(verifyKeys))}
+	for _, k := range verifyKeys {
+		jwk := JWK{Kid: k.kid, Use: "sig", Alg: k.method.Alg()}
+		switch pub := k.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
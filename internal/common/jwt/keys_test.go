@@ -0,0 +1,260 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"godir/internal/common/svc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// testKeys 测试用密钥：current 为当前签名密钥（RS256），retired 为已下线只保留公钥的旧密钥（RS256），
+// ed 为 EdDSA 密钥
+type testKeys struct {
+	current *rsa.PrivateKey
+	retired *rsa.PrivateKey
+	ed      ed25519.PrivateKey
+}
+
+// setupKeys 写出PEM文件并按配置初始化，返回各密钥的私钥供测试签名
+func setupKeys(t *testing.T) *testKeys {
+	t.Helper()
+	dir := t.TempDir()
+
+	current, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := svc.JWTConfig{
+		SecretKey:    testSecret,
+		SigningKeyID: "current",
+		Keys: []svc.JWTKeyConfig{
+			{ID: "current", Algorithm: "RS256", PrivateKeyFile: writePrivateKey(t, dir, "current.pem", current)},
+			{ID: "retired", Algorithm: "RS256", PublicKeyFile: writePublicKey(t, dir, "retired.pub.pem", &retired.PublicKey)},
+			{ID: "ed", Algorithm: "EdDSA", PrivateKeyFile: writePrivateKey(t, dir, "ed.pem", ed)},
+		},
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() {
+		secretKey, signer, verifyKeys = nil, nil, nil
+	})
+
+	return &testKeys{current: current, retired: retired, ed: ed}
+}
+
+func writePrivateKey(t *testing.T, dir, name string, k any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, name, "PRIVATE KEY", der)
+}
+
+func writePublicKey(t *testing.T, dir, name string, k any) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, name, "PUBLIC KEY", der)
+}
+
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// sign 用指定算法、kid 和密钥签发测试token，kid 为空时不写入头部
+func sign(t *testing.T, method jwt.SigningMethod, kid string, k any) string {
+	t.Helper()
+	claims := Claims{
+		UserID:   1,
+		Username: "alice",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(k)
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+	return s
+}
+
+func TestParseTokenKeySelection(t *testing.T) {
+	keys := setupKeys(t)
+
+	// HS256 头部携带RSA公钥的kid，并以公钥的DER作为HMAC密钥：典型的算法混淆攻击
+	rsaPubDER, _ := x509.MarshalPKIXPublicKey(&keys.current.PublicKey)
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"当前签名密钥", sign(t, jwt.SigningMethodRS256, "current", keys.current), true},
+		{"已下线密钥签发的旧token", sign(t, jwt.SigningMethodRS256, "retired", keys.retired), true},
+		{"EdDSA密钥", sign(t, jwt.SigningMethodEdDSA, "ed", keys.ed), true},
+		{"无kid的旧HS256 token", sign(t, jwt.SigningMethodHS256, "", []byte(testSecret)), true},
+
+		{"未知kid", sign(t, jwt.SigningMethodRS256, "unknown", keys.current), false},
+		{"kid正确但签名密钥不符", sign(t, jwt.SigningMethodRS256, "retired", other), false},
+		{"HS256头部携带RSA kid", sign(t, jwt.SigningMethodHS256, "current", rsaPubDER), false},
+		{"HS256头部携带RSA kid（用共享密钥签名）", sign(t, jwt.SigningMethodHS256, "current", []byte(testSecret)), false},
+		{"EdDSA签名携带RSA kid", sign(t, jwt.SigningMethodEdDSA, "current", keys.ed), false},
+		{"RS256签名携带EdDSA kid", sign(t, jwt.SigningMethodRS256, "ed", keys.current), false},
+		{"无kid的RS256 token", sign(t, jwt.SigningMethodRS256, "", keys.current), false},
+		{"无kid的HS256 token但密钥错误", sign(t, jwt.SigningMethodHS256, "", []byte("wrong")), false},
+	}
+
+	for _, tt := range tests {
+		claims, err := ParseToken(tt.token)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%s: 应校验通过, err = %v", tt.name, err)
+			} else if claims.UserID != 1 {
+				t.Errorf("%s: UserID = %d, want 1", tt.name, claims.UserID)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: 应校验失败", tt.name)
+		}
+	}
+}
+
+// 未配置 SecretKey 时不再接受无kid的HS256 token
+func TestParseTokenWithoutSecretRejectsHS256(t *testing.T) {
+	dir := t.TempDir()
+	k, _ := rsa.GenerateKey(rand.Reader, 2048)
+	cfg := svc.JWTConfig{
+		SigningKeyID: "current",
+		Keys:         []svc.JWTKeyConfig{{ID: "current", Algorithm: "RS256", PrivateKeyFile: writePrivateKey(t, dir, "k.pem", k)}},
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	t.Cleanup(func() {
+		secretKey, signer, verifyKeys = nil, nil, nil
+	})
+
+	if _, err := ParseToken(sign(t, jwt.SigningMethodHS256, "", []byte(""))); err == nil {
+		t.Fatal("未配置SecretKey时无kid的HS256 token应校验失败")
+	}
+	if _, err := ParseToken(sign(t, jwt.SigningMethodRS256, "current", k)); err != nil {
+		t.Fatalf("RS256 token应校验通过: %v", err)
+	}
+}
+
+func TestLoadKeysRejectsInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaPath := writePrivateKey(t, dir, "rsa.pem", rsaKey)
+	pubPath := writePublicKey(t, dir, "rsa.pub.pem", &rsaKey.PublicKey)
+	t.Cleanup(func() {
+		secretKey, signer, verifyKeys = nil, nil, nil
+	})
+
+	tests := []struct {
+		name string
+		cfg  svc.JWTConfig
+	}{
+		{"RSA密钥配置为EdDSA", svc.JWTConfig{Keys: []svc.JWTKeyConfig{{ID: "a", Algorithm: "EdDSA", PrivateKeyFile: rsaPath}}}},
+		{"不支持的算法", svc.JWTConfig{Keys: []svc.JWTKeyConfig{{ID: "a", Algorithm: "HS256", PrivateKeyFile: rsaPath}}}},
+		{"kid重复", svc.JWTConfig{Keys: []svc.JWTKeyConfig{
+			{ID: "a", Algorithm: "RS256", PrivateKeyFile: rsaPath},
+			{ID: "a", Algorithm: "RS256", PublicKeyFile: pubPath},
+		}}},
+		{"签名密钥未配置", svc.JWTConfig{SigningKeyID: "b", Keys: []svc.JWTKeyConfig{{ID: "a", Algorithm: "RS256", PrivateKeyFile: rsaPath}}}},
+		{"签名密钥只有公钥", svc.JWTConfig{SigningKeyID: "a", Keys: []svc.JWTKeyConfig{{ID: "a", Algorithm: "RS256", PublicKeyFile: pubPath}}}},
+	}
+
+	for _, tt := range tests {
+		if err := loadKeys(tt.cfg); err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	keys := setupKeys(t)
+
+	set := JWKS()
+	if len(set.Keys) != 3 {
+		t.Fatalf("len(keys) = %d, want 3", len(set.Keys))
+	}
+
+	// 按kid排序输出，且包含只有公钥的旧密钥
+	wantOrder := []string{"current", "ed", "retired"}
+	for i, kid := range wantOrder {
+		if set.Keys[i].Kid != kid {
+			t.Fatalf("keys[%d].kid = %s, want %s", i, set.Keys[i].Kid, kid)
+		}
+	}
+
+	rsaKeys := map[string]*rsa.PublicKey{"current": &keys.current.PublicKey, "retired": &keys.retired.PublicKey}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "sig" {
+			t.Errorf("%s: use = %s, want sig", jwk.Kid, jwk.Use)
+		}
+
+		if pub, ok := rsaKeys[jwk.Kid]; ok {
+			if jwk.Kty != "RSA" || jwk.Alg != "RS256" || jwk.Crv != "" || jwk.X != "" {
+				t.Errorf("%s: 字段不符 %+v", jwk.Kid, jwk)
+			}
+			n, _ := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, _ := base64.RawURLEncoding.DecodeString(jwk.E)
+			if new(big.Int).SetBytes(n).Cmp(pub.N) != 0 {
+				t.Errorf("%s: n 与公钥不一致", jwk.Kid)
+			}
+			if int(new(big.Int).SetBytes(e).Int64()) != pub.E {
+				t.Errorf("%s: e = %x, want %d", jwk.Kid, e, pub.E)
+			}
+			if jwk.E != "AQAB" {
+				t.Errorf("%s: e = %s, want AQAB", jwk.Kid, jwk.E)
+			}
+			continue
+		}
+
+		if jwk.Kty != "OKP" || jwk.Crv != "Ed25519" || jwk.Alg != "EdDSA" || jwk.N != "" || jwk.E != "" {
+			t.Errorf("%s: 字段不符 %+v", jwk.Kid, jwk)
+		}
+		x, _ := base64.RawURLEncoding.DecodeString(jwk.X)
+		if !ed25519.PublicKey(x).Equal(keys.ed.Public()) {
+			t.Errorf("%s: x 与公钥不一致", jwk.Kid)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package jwt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"godir/internal/common/svc"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	revokedKeyPrefix        = "jwt:revoked:"
+	generationKeyPrefix     = "jwt:gen:"
+	sessionRevokedKeyPrefix = "jwt:session:revoked:"
+)
+
+// Revoke 吊销单个token，黑名单的过期时间与token剩余有效期一致
+func Revoke(ctx context.Context, claims *Claims) error {
+	if claims.ID == "" {
+		return fmt.Errorf("token缺少jti，无法吊销")
+	}
+
+	ttl := time.Minute
+	if claims.ExpiresAt != nil {
+		ttl = time.Until(claims.ExpiresAt.Time)
+		if ttl <= 0 {
+			// 已过期的token无需再记录
+			return nil
+		}
+	}
+
+	return svc.Redis().Set(ctx, revokedKeyPrefix+claims.ID, 1, ttl).Err()
+}
+
+// RevokeAll 使用户当前持有的所有token失效（递增用户token代数）
+func RevokeAll(ctx context.Context, userID uint) (int64, error) {
+	return svc.Redis().Incr(ctx, generationKey(userID)).Result()
+}
+
+// Generation 获取用户当前的token代数，未设置时为0
+func Generation(ctx context.Context, userID uint) (int64, error) {
+	gen, err := svc.Redis().Get(ctx, generationKey(userID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return gen, err
+}
+
+// RevokeSession 吊销会话，该会话下尚未过期的访问token随之失效
+// 会话的刷新token需由调用方在库中吊销，因此标记只需保留一个访问token有效期
+func RevokeSession(ctx context.Context, sessionID string) error {
+	return svc.Redis().Set(ctx, sessionRevokedKeyPrefix+sessionID, 1, tokenExp).Err()
+}
+
+// IsRevoked 判断token是否已被吊销（单独吊销、所属会话被吊销或代数落后）
+func IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	keys := make([]string, 0, 2)
+	if claims.ID != "" {
+		keys = append(keys, revokedKeyPrefix+claims.ID)
+	}
+	if claims.Sid != "" {
+		keys = append(keys, sessionRevokedKeyPrefix+claims.Sid)
+	}
+	if len(keys) > 0 {
+		n, err := svc.Redis().Exists(ctx, keys...).Result()
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+
+	gen, err := Generation(ctx, claims.UserID)
+	if err != nil {
+		return false, err
+	}
+	return claims.Gen < gen, nil
+}
+
+func generationKey(userID uint) string {
+	return fmt.Sprintf("%s%d", generationKeyPrefix, userID)
+}
@@ -0,0 +1,61 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"godir/internal/common/svc"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	streamTicketKeyPrefix = "jwt:ticket:"
+
+	// StreamTicketTTL 票据的有效期，只需覆盖从签发到建立连接的间隔
+	StreamTicketTTL = time.Minute
+)
+
+// IssueStreamTicket 为当前登录token签发一次性短期票据，供 EventSource 等无法设置请求头的连接放在URL中；
+// 服务端只保存票据的哈希，票据使用一次或过期后即失效，URL被记录到日志也不会泄露登录token
+func IssueStreamTicket(ctx context.Context, claims *Claims) (string, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	ticket := base64.RawURLEncoding.EncodeToString(b)
+
+	if err := svc.Redis().Set(ctx, streamTicketKey(ticket), data, StreamTicketTTL).Err(); err != nil {
+		return "", err
+	}
+	return ticket, nil
+}
+
+// ConsumeStreamTicket 校验并作废票据，返回签发票据时的登录信息；调用方仍需检查其是否已被吊销
+func ConsumeStreamTicket(ctx context.Context, ticket string) (*Claims, error) {
+	data, err := svc.Redis().GetDel(ctx, streamTicketKey(ticket)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, errors.New("票据无效或已使用")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("解析票据失败: %w", err)
+	}
+	return &claims, nil
+}
+
+func streamTicketKey(ticket string) string {
+	return streamTicketKeyPrefix + HashRefreshToken(ticket)
+}
@@ -66,6 +66,8 @@ func InitWithConfig(cfg LogConfig) *zap.SugaredLogger {
 
 	// 创建logger
 	Logger = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)).Sugar()
+	// 同步到 zap 全局 logger，供无法依赖本包的底层包（如 mailer）使用
+	zap.ReplaceGlobals(Logger.Desugar())
 	return Logger
 }
 
@@ -0,0 +1,172 @@
+package loginguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"godir/internal/common/svc"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyPrefix = "auth:login:"
+)
+
+// Block 登录被限制的原因及需要等待的时间
+type Block struct {
+	Locked     bool          // true: 账号或IP被锁定；false: 处于退避等待期
+	RetryAfter time.Duration // 还需等待的时间
+}
+
+// Result 记录一次失败后的状态
+type Result struct {
+	UserFailures int64 // 窗口内该用户名的失败次数
+	IPFailures   int64 // 窗口内该IP的失败次数
+	UserLocked   bool  // 本次失败导致账号被锁定
+	IPLocked     bool  // 本次失败导致IP被锁定
+}
+
+type options struct {
+	window        time.Duration
+	maxFailures   int64
+	ipMaxFailures int64
+	lockDuration  time.Duration
+	backoffBase   time.Duration
+	backoffMax    time.Duration
+}
+
+func loadOptions() options {
+	cfg := svc.Cfg().LoginGuard
+	o := options{
+		window:        parseDuration(cfg.Window, 15*time.Minute),
+		maxFailures:   int64(cfg.MaxFailures),
+		ipMaxFailures: int64(cfg.IPMaxFailures),
+		lockDuration:  parseDuration(cfg.LockDuration, 15*time.Minute),
+		backoffBase:   parseDuration(cfg.BackoffBase, time.Second),
+		backoffMax:    parseDuration(cfg.BackoffMax, time.Minute),
+	}
+	if o.maxFailures <= 0 {
+		o.maxFailures = 5
+	}
+	if o.ipMaxFailures <= 0 {
+		o.ipMaxFailures = 50
+	}
+	return o
+}
+
+func parseDuration(s string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// Check 登录前检查用户名和IP是否被锁定或处于退避期，返回nil表示允许尝试
+func Check(ctx context.Context, username, ip string) (*Block, error) {
+	rdb := svc.Redis()
+
+	for _, key := range []string{lockKey("user", username), lockKey("ip", ip)} {
+		ttl, err := rdb.PTTL(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ttl > 0 {
+			return &Block{Locked: true, RetryAfter: ttl}, nil
+		}
+	}
+
+	ttl, err := rdb.PTTL(ctx, backoffKey(username)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if ttl > 0 {
+		return &Block{RetryAfter: ttl}, nil
+	}
+	return nil, nil
+}
+
+// Fail 记录一次失败：更新滑动窗口计数，设置指数退避，超过阈值时锁定
+func Fail(ctx context.Context, username, ip string) (*Result, error) {
+	o := loadOptions()
+	rdb := svc.Redis()
+
+	userFailures, err := record(ctx, rdb, failKey("user", username), o.window)
+	if err != nil {
+		return nil, err
+	}
+	ipFailures, err := record(ctx, rdb, failKey("ip", ip), o.window)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{UserFailures: userFailures, IPFailures: ipFailures}
+
+	// 指数退避：第n次失败后需等待 base*2^(n-1)，不超过上限
+	backoff := o.backoffBase << (userFailures - 1)
+	if backoff <= 0 || backoff > o.backoffMax {
+		backoff = o.backoffMax
+	}
+	if err := rdb.Set(ctx, backoffKey(username), 1, backoff).Err(); err != nil {
+		return nil, err
+	}
+
+	if userFailures >= o.maxFailures {
+		ok, err := rdb.SetNX(ctx, lockKey("user", username), 1, o.lockDuration).Result()
+		if err != nil {
+			return nil, err
+		}
+		res.UserLocked = ok
+		// 锁定后清空计数，解锁后重新累计
+		rdb.Del(ctx, failKey("user", username))
+	}
+	if ipFailures >= o.ipMaxFailures {
+		ok, err := rdb.SetNX(ctx, lockKey("ip", ip), 1, o.lockDuration).Result()
+		if err != nil {
+			return nil, err
+		}
+		res.IPLocked = ok
+		rdb.Del(ctx, failKey("ip", ip))
+	}
+
+	return res, nil
+}
+
+// Succeed 登录成功后清除该用户名的失败记录
+func Succeed(ctx context.Context, username string) error {
+	return svc.Redis().Del(ctx, failKey("user", username), backoffKey(username)).Err()
+}
+
+// LockDuration 当前配置的锁定时长
+func LockDuration() time.Duration {
+	return loadOptions().lockDuration
+}
+
+// record 在有序集合中追加一次失败并剔除窗口外的记录，返回窗口内的次数
+func record(ctx context.Context, rdb *redis.Client, key string, window time.Duration) (int64, error) {
+	now := time.Now()
+	pipe := rdb.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "0", fmt.Sprintf("%d", now.Add(-window).UnixNano()))
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixNano()), Member: now.UnixNano()})
+	count := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+func failKey(kind, id string) string {
+	return keyPrefix + "fail:" + kind + ":" + strings.ToLower(id)
+}
+
+func lockKey(kind, id string) string {
+	return keyPrefix + "lock:" + kind + ":" + strings.ToLower(id)
+}
+
+func backoffKey(username string) string {
+	return keyPrefix + "backoff:" + strings.ToLower(username)
+}
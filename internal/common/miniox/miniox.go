@@ -1,13 +1,21 @@
 package miniox
 
 import (
+	"encoding/json"
 	"fmt"
 	"godir/internal/common/svc"
+	"strings"
 	"time"
 
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
+// 上传凭证有效期的默认值和下限，MinIO要求 DurationSeconds 不小于900
+const (
+	defaultUploadTokenExp = 15 * time.Minute
+	minUploadTokenExp     = 15 * time.Minute
+)
+
 func BuildBaseUrl(useSSL bool, endpoint string) string {
 	// 更新数据库中的封面信息
 	protocol := "http"
@@ -31,33 +39,44 @@ type Credentials struct {
 	Expiration time.Time
 }
 
-func TemporaryCredentials(cfg *svc.Config, paths []string) (*Credentials, error) {
+// UploadTokenExp 上传凭证有效期
+func UploadTokenExp(cfg *svc.Config) time.Duration {
+	exp, err := time.ParseDuration(cfg.MinIO.UploadTokenExp)
+	if err != nil || exp <= 0 {
+		return defaultUploadTokenExp
+	}
+	if exp < minUploadTokenExp {
+		return minUploadTokenExp
+	}
+	return exp
+}
+
+// TemporaryCredentials 通过STS签发只允许向指定key上传的临时凭证
+// 会话策略只授予这些对象的 PutObject（分片上传同样由该权限覆盖）和 AbortMultipartUpload
+func TemporaryCredentials(cfg *svc.Config, keys []string) (*Credentials, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("未指定上传对象")
+	}
+
 	stsEndpoint := BuildBaseUrl(cfg.MinIO.UseSSL, cfg.MinIO.Endpoint) // STS服务端点，通常与MinIO相同
 
+	accessKey, secretKey := cfg.MinIO.STSAccessKeyID, cfg.MinIO.STSSecretAccessKey
+	if accessKey == "" {
+		accessKey, secretKey = cfg.MinIO.AccessKeyID, cfg.MinIO.SecretAccessKey
+	}
+
+	policy, err := putObjectPolicy(cfg.MinIO.Bucket, keys)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := credentials.STSAssumeRoleOptions{
-		AccessKey: cfg.MinIO.AccessKeyID,                              // 有权限调用STS的Access Key
-		SecretKey: cfg.MinIO.SecretAccessKey,                          // 对应的Secret Key
-		Location:  cfg.MinIO.Region,                                   // 区域，MinIO中可自定义
-		RoleARN:   fmt.Sprintf("arn:aws:s3:::%s/*", cfg.MinIO.Bucket), // test是桶名称
+		AccessKey:       accessKey,
+		SecretKey:       secretKey,
+		Location:        cfg.MinIO.Region,
+		DurationSeconds: int(UploadTokenExp(cfg).Seconds()),
+		Policy:          policy,
 	}
-	// for i, v := range paths {
-	// 	paths[i] = fmt.Sprintf("arn:aws:s3:::%s/%s", cfg.MinIO.Bucket, strings.TrimPrefix(v, "/"))
-	// }
-	policy := `{
-				"Version": "2012-10-17",
-				"Statement": [
-					{
-						"Effect": "Allow",
-						"Action": [
-							"*"
-						],
-						"Resource": [
-							"arn:aws:s3:::*"
-						]
-					}
-				]
-			}`
-	opts.Policy = policy
 	creds, err := credentials.NewSTSAssumeRole(stsEndpoint, opts)
 	if err != nil {
 		return nil, err
@@ -74,3 +93,31 @@ func TemporaryCredentials(cfg *svc.Config, paths []string) (*Credentials, error)
 		Expiration:      value.Expiration,
 	}, nil
 }
+
+// putObjectPolicy 生成只允许上传指定对象的会话策略
+func putObjectPolicy(bucket string, keys []string) (string, error) {
+	resources := make([]string, 0, len(keys))
+	for _, key := range keys {
+		key = strings.TrimPrefix(key, "/")
+		if key == "" || strings.ContainsAny(key, "*?") {
+			return "", fmt.Errorf("非法的对象key: %q", key)
+		}
+		resources = append(resources, fmt.Sprintf("arn:aws:s3:::%s/%s", bucket, key))
+	}
+
+	policy := map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{
+			{
+				"Effect":   "Allow",
+				"Action":   []string{"s3:PutObject", "s3:AbortMultipartUpload"},
+				"Resource": resources,
+			},
+		},
+	}
+	b, err := json.Marshal(policy)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,77 @@
+package miniox
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPutObjectPolicyGolden(t *testing.T) {
+	got, err := putObjectPolicy("godir", []string{"upload/1/20260101/abc.jpg", "/upload/1/20260101/def.png"})
+	if err != nil {
+		t.Fatalf("putObjectPolicy: %v", err)
+	}
+
+	const want = `{"Statement":[{` +
+		`"Action":["s3:PutObject","s3:AbortMultipartUpload"],` +
+		`"Effect":"Allow",` +
+		`"Resource":["arn:aws:s3:::godir/upload/1/20260101/abc.jpg","arn:aws:s3:::godir/upload/1/20260101/def.png"]` +
+		`}],"Version":"2012-10-17"}`
+	if got != want {
+		t.Fatalf("policy =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// 策略只能授予写入指定对象，不能出现读取、删除或通配的资源
+func TestPutObjectPolicyScope(t *testing.T) {
+	got, err := putObjectPolicy("godir", []string{"upload/1/20260101/abc.jpg"})
+	if err != nil {
+		t.Fatalf("putObjectPolicy: %v", err)
+	}
+
+	var policy struct {
+		Statement []struct {
+			Effect   string
+			Action   []string
+			Resource []string
+		}
+	}
+	if err := json.Unmarshal([]byte(got), &policy); err != nil {
+		t.Fatalf("策略不是合法JSON: %v", err)
+	}
+	if len(policy.Statement) != 1 {
+		t.Fatalf("len(Statement) = %d, want 1", len(policy.Statement))
+	}
+
+	st := policy.Statement[0]
+	allowed := map[string]bool{"s3:PutObject": true, "s3:AbortMultipartUpload": true}
+	if st.Effect != "Allow" || len(st.Action) != len(allowed) {
+		t.Fatalf("statement = %+v", st)
+	}
+	for _, a := range st.Action {
+		if !allowed[a] {
+			t.Errorf("不应授予的操作: %s", a)
+		}
+	}
+	if len(st.Resource) != 1 || st.Resource[0] != "arn:aws:s3:::godir/upload/1/20260101/abc.jpg" {
+		t.Errorf("Resource = %v", st.Resource)
+	}
+}
+
+func TestPutObjectPolicyRejectsInvalidKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+	}{
+		{"空key", []string{""}},
+		{"只有斜杠", []string{"/"}},
+		{"星号通配", []string{"upload/1/*"}},
+		{"问号通配", []string{"upload/1/a?c"}},
+		{"其中一个非法", []string{"upload/1/a.jpg", "upload/*"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := putObjectPolicy("godir", tt.keys); err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+		}
+	}
+}
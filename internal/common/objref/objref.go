@@ -0,0 +1,186 @@
+// Package objref 维护存储对象的引用计数，以及内容哈希的计算
+package objref
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+
+	"godir/internal/common/svc"
+	"godir/internal/model"
+
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultHashSyncLimit = 64 << 20
+
+// HashSyncLimit 保存时同步计算哈希的文件大小上限
+func HashSyncLimit() int64 {
+	if limit := svc.Cfg().Storage.HashSyncLimit; limit > 0 {
+		return limit
+	}
+	return defaultHashSyncLimit
+}
+
+// Hash 读取整个对象计算SHA-256
+func Hash(ctx context.Context, bucket, key string) (string, error) {
+	obj, err := svc.Minio().GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer obj.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, obj); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Acquire 新素材引用对象后调用（素材记录须已写入），需与素材记录在同一事务中。
+// 早于引用计数上线的对象没有计数记录，首次引用时按素材表统计现有引用数
+func Acquire(tx *gorm.DB, bucket, key, hash string, size int64) error {
+	obj, err := lock(tx, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	if obj == nil {
+		refs, err := countRefs(tx, bucket, key)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&model.GodirObject{
+			Bucket:      bucket,
+			ObjectKey:   key,
+			ContentHash: hash,
+			Size:        size,
+			RefCount:    int(refs),
+		}).Error
+	}
+
+	updates := map[string]interface{}{"ref_count": gorm.Expr("ref_count + 1")}
+	if obj.ContentHash == "" && hash != "" {
+		updates["content_hash"] = hash
+	}
+	return tx.Model(obj).UpdateColumns(updates).Error
+}
+
+// Release 素材记录彻底删除后调用，需在同一事务中；返回对象是否已无引用、可以删除
+func Release(tx *gorm.DB, bucket, key string) (bool, error) {
+	obj, err := lock(tx, bucket, key)
+	if err != nil {
+		return false, err
+	}
+
+	if obj == nil {
+		refs, err := countRefs(tx, bucket, key)
+		return refs == 0, err
+	}
+
+	if obj.RefCount <= 1 {
+		return true, tx.Delete(obj).Error
+	}
+	return false, tx.Model(obj).UpdateColumn("ref_count", gorm.Expr("ref_count - 1")).Error
+}
+
+// SetHash 后台计算出哈希后回填
+func SetHash(db *gorm.DB, bucket, key, hash string) error {
+	if err := db.Model(&model.GodirMaterial{}).Unscoped().
+		Where("oss_bucket = ? AND oss_file_path = ? AND content_hash = ''", bucket, key).
+		Update("content_hash", hash).Error; err != nil {
+		return err
+	}
+	return db.Model(&model.GodirObject{}).
+		Where("bucket = ? AND object_key = ? AND content_hash = ''", bucket, key).
+		Update("content_hash", hash).Error
+}
+
+// Reused 素材改为引用已有对象后的结果
+type Reused struct {
+	SourceID uint   // 被复用对象的素材
+	Bucket   string // 素材原来引用的对象
+	Key      string
+	Orphaned bool // 原对象已无引用，调用方可以删除
+}
+
+// Reuse 素材改为引用同一用户内容相同的最早一个素材的对象，用于后台算出大文件哈希后执行保存时要求的复用；
+// 素材已删除、哈希为空或没有内容相同的其他对象时返回nil
+func Reuse(db *gorm.DB, materialID uint) (*Reused, error) {
+	var result *Reused
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var m model.GodirMaterial
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&m, materialID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if m.ContentHash == "" {
+			return nil
+		}
+
+		var source model.GodirMaterial
+		err = tx.Where("user_id = ? AND content_hash = ? AND id <> ?", m.UserID, m.ContentHash, m.ID).
+			Where("NOT (oss_bucket = ? AND oss_file_path = ?)", m.OssBucket, m.OssFilePath).
+			Order("id").
+			First(&source).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// 先改写素材再调整计数：没有计数记录的对象按素材表统计，此时统计结果已是改写后的
+		updates := map[string]interface{}{"oss_bucket": source.OssBucket, "oss_file_path": source.OssFilePath}
+		if m.CoverOssFilePath == "" && source.CoverOssFilePath != "" {
+			updates["cover_oss_file_path"] = source.CoverOssFilePath
+		}
+		if err := tx.Model(&model.GodirMaterial{}).Where("id = ?", m.ID).UpdateColumns(updates).Error; err != nil {
+			return err
+		}
+		if err := Acquire(tx, source.OssBucket, source.OssFilePath, m.ContentHash, source.FileSize); err != nil {
+			return err
+		}
+		orphaned, err := Release(tx, m.OssBucket, m.OssFilePath)
+		if err != nil {
+			return err
+		}
+
+		result = &Reused{SourceID: source.ID, Bucket: m.OssBucket, Key: m.OssFilePath, Orphaned: orphaned}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func lock(tx *gorm.DB, bucket, key string) (*model.GodirObject, error) {
+	var obj model.GodirObject
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("bucket = ? AND object_key = ?", bucket, key).
+		First(&obj).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &obj, nil
+}
+
+// countRefs 引用该对象的素材数（含回收站中的）
+func countRefs(tx *gorm.DB, bucket, key string) (int64, error) {
+	var count int64
+	err := tx.Model(&model.GodirMaterial{}).Unscoped().
+		Where("oss_bucket = ? AND oss_file_path = ?", bucket, key).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,105 @@
+package objref
+
+import (
+	"testing"
+
+	"godir/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.GodirMaterial{}, &model.GodirObject{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// addMaterial 登记素材并引用其对象，与保存流程一致
+func addMaterial(t *testing.T, db *gorm.DB, userID uint, key, hash string) *model.GodirMaterial {
+	t.Helper()
+	m := &model.GodirMaterial{UserID: userID, FileName: key, OssBucket: "b", OssFilePath: key, ContentHash: hash, FileSize: 10}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(m).Error; err != nil {
+			return err
+		}
+		return Acquire(tx, "b", key, hash, 10)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func refCount(t *testing.T, db *gorm.DB, key string) int {
+	t.Helper()
+	var obj model.GodirObject
+	if err := db.Where("bucket = ? AND object_key = ?", "b", key).First(&obj).Error; err != nil {
+		return 0
+	}
+	return obj.RefCount
+}
+
+func TestReuse(t *testing.T) {
+	db := newTestDB(t)
+	source := addMaterial(t, db, 1, "user/1/a", "h1")
+	addMaterial(t, db, 2, "user/2/other", "h1") // 其他用户的相同内容不参与复用
+	dup := addMaterial(t, db, 1, "upload/1/big", "h1")
+
+	reused, err := Reuse(db, dup.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reused == nil {
+		t.Fatal("应复用已有对象")
+	}
+	if reused.SourceID != source.ID || reused.Key != "upload/1/big" || !reused.Orphaned {
+		t.Fatalf("reused = %+v", reused)
+	}
+
+	var got model.GodirMaterial
+	if err := db.First(&got, dup.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.OssFilePath != source.OssFilePath {
+		t.Fatalf("oss_file_path = %s, want %s", got.OssFilePath, source.OssFilePath)
+	}
+	if n := refCount(t, db, source.OssFilePath); n != 2 {
+		t.Fatalf("被复用对象 ref_count = %d, want 2", n)
+	}
+	if n := refCount(t, db, "upload/1/big"); n != 0 {
+		t.Fatalf("原对象 ref_count = %d, want 0", n)
+	}
+
+	// 已经复用过的再次执行不做任何修改
+	again, err := Reuse(db, dup.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != nil {
+		t.Fatalf("重复执行 reused = %+v, want nil", again)
+	}
+}
+
+func TestReuseWithoutDuplicate(t *testing.T) {
+	db := newTestDB(t)
+	m := addMaterial(t, db, 1, "user/1/a", "h1")
+	noHash := addMaterial(t, db, 1, "user/1/b", "")
+
+	for _, id := range []uint{m.ID, noHash.ID, 999} {
+		reused, err := Reuse(db, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reused != nil {
+			t.Fatalf("material %d: reused = %+v, want nil", id, reused)
+		}
+	}
+}
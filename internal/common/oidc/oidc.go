@@ -0,0 +1,355 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"godir/internal/common/svc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Provider 一个已完成服务发现的OIDC身份提供方
+type Provider struct {
+	cfg svc.OIDCProviderConfig
+
+	issuer                string
+	authorizationEndpoint string
+	tokenEndpoint         string
+	jwksURI               string
+
+	mu   sync.RWMutex
+	keys map[string]interface{} // kid -> 公钥
+}
+
+// IDClaims ID Token 中用到的声明
+type IDClaims struct {
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Name              string `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// providerEntry 单个身份提供方的缓存项，服务发现在各自的锁内进行：
+// 同一提供方的并发请求只发现一次，某个提供方响应慢也不会阻塞其他提供方
+type providerEntry struct {
+	mu sync.Mutex
+	p  *Provider
+}
+
+var (
+	providersMu sync.Mutex // 只保护 providers 映射本身，不在持有期间发起网络请求
+	providers   = map[string]*providerEntry{}
+)
+
+// Get 按名称获取身份提供方，首次使用时进行服务发现并缓存；发现失败不缓存，下次请求重试
+func Get(ctx context.Context, name string) (*Provider, error) {
+	cfg, ok := providerConfig(name)
+	if !ok {
+		return nil, fmt.Errorf("未配置的身份提供方: %s", name)
+	}
+
+	providersMu.Lock()
+	e, ok := providers[name]
+	if !ok {
+		e = &providerEntry{}
+		providers[name] = e
+	}
+	providersMu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.p != nil {
+		return e.p, nil
+	}
+
+	p, err := discover(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	e.p = p
+	return p, nil
+}
+
+func providerConfig(name string) (svc.OIDCProviderConfig, bool) {
+	for _, cfg := range svc.Cfg().OIDC {
+		if cfg.Name == name {
+			return cfg, true
+		}
+	}
+	return svc.OIDCProviderConfig{}, false
+}
+
+// Names 返回所有已配置的身份提供方名称
+func Names() []string {
+	names := make([]string, 0, len(svc.Cfg().OIDC))
+	for _, cfg := range svc.Cfg().OIDC {
+		names = append(names, cfg.Name)
+	}
+	return names
+}
+
+// Config 返回身份提供方配置
+func (p *Provider) Config() svc.OIDCProviderConfig {
+	return p.cfg
+}
+
+func discover(ctx context.Context, cfg svc.OIDCProviderConfig) (*Provider, error) {
+	wellKnown := strings.TrimRight(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		JWKSURI               string `json:"jwks_uri"`
+	}
+	if err := getJSON(ctx, wellKnown, &doc); err != nil {
+		return nil, fmt.Errorf("OIDC服务发现失败: %w", err)
+	}
+	if doc.Issuer != cfg.Issuer {
+		return nil, fmt.Errorf("OIDC issuer不匹配: 配置为%s，实际为%s", cfg.Issuer, doc.Issuer)
+	}
+
+	return &Provider{
+		cfg:                   cfg,
+		issuer:                doc.Issuer,
+		authorizationEndpoint: doc.AuthorizationEndpoint,
+		tokenEndpoint:         doc.TokenEndpoint,
+		jwksURI:               doc.JWKSURI,
+	}, nil
+}
+
+// AuthURL 构造授权码+PKCE登录地址
+func (p *Provider) AuthURL(state, nonce, verifier string) string {
+	scopes := p.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.cfg.ClientID)
+	v.Set("redirect_uri", p.cfg.RedirectURL)
+	v.Set("scope", strings.Join(scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", challenge(verifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(p.authorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.authorizationEndpoint + sep + v.Encode()
+}
+
+// Exchange 用授权码换取token并校验ID Token
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*IDClaims, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.cfg.ClientID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求token端点失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token端点返回%d: %s", resp.StatusCode, string(body))
+	}
+
+	var tok struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return nil, fmt.Errorf("解析token响应失败: %w", err)
+	}
+	if tok.IDToken == "" {
+		return nil, errors.New("token响应中缺少id_token")
+	}
+
+	return p.verifyIDToken(ctx, tok.IDToken, nonce)
+}
+
+// verifyIDToken 校验ID Token的签名、签发方、受众、有效期和nonce
+func (p *Provider) verifyIDToken(ctx context.Context, raw, nonce string) (*IDClaims, error) {
+	claims := &IDClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ID Token校验失败: %w", err)
+	}
+
+	if claims.Nonce != nonce {
+		return nil, errors.New("ID Token nonce不匹配")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("ID Token缺少sub")
+	}
+	return claims, nil
+}
+
+// key 按kid查找公钥，找不到时刷新一次JWKS以支持提供方轮换密钥
+func (p *Provider) key(ctx context.Context, kid string) (interface{}, error) {
+	p.mu.RLock()
+	k, ok := p.lookup(kid)
+	p.mu.RUnlock()
+	if ok {
+		return k, nil
+	}
+
+	if err := p.refreshKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if k, ok := p.lookup(kid); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("未找到kid为%s的公钥", kid)
+}
+
+// lookup 调用方需持有读锁；kid为空且只有一把密钥时直接使用该密钥
+func (p *Provider) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, k := range p.keys {
+			return k, true
+		}
+	}
+	k, ok := p.keys[kid]
+	return k, ok
+}
+
+func (p *Provider) refreshKeys(ctx context.Context) error {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := getJSON(ctx, p.jwksURI, &set); err != nil {
+		return fmt.Errorf("获取JWKS失败: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+	return nil
+}
+
+func getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回 %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// NewRandom 生成用于state、nonce、PKCE verifier的随机串
+func NewRandom() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// challenge 计算PKCE S256 code_challenge
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,121 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"godir/internal/common/svc"
+)
+
+// newIssuer 模拟身份提供方的服务发现接口，release 关闭前请求一直挂起（为 nil 时立即返回）
+func newIssuer(t *testing.T, release <-chan struct{}, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if release != nil {
+			<-release
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 srv.URL,
+			"authorization_endpoint": srv.URL + "/authorize",
+			"token_endpoint":         srv.URL + "/token",
+			"jwks_uri":               srv.URL + "/jwks",
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func setupProviders(t *testing.T, cfgs ...svc.OIDCProviderConfig) {
+	t.Helper()
+	prev := svc.Get().Cfg
+	svc.Get().Cfg = &svc.Config{OIDC: cfgs}
+	t.Cleanup(func() {
+		svc.Get().Cfg = prev
+		providers = map[string]*providerEntry{}
+	})
+}
+
+// 一个提供方的服务发现挂起时，其他提供方不受影响
+func TestGetDoesNotBlockOtherProviders(t *testing.T) {
+	release := make(chan struct{})
+	var slowHits, fastHits atomic.Int32
+	slow := newIssuer(t, release, &slowHits)
+	fast := newIssuer(t, nil, &fastHits)
+	defer close(release)
+
+	setupProviders(t,
+		svc.OIDCProviderConfig{Name: "slow", Issuer: slow.URL},
+		svc.OIDCProviderConfig{Name: "fast", Issuer: fast.URL},
+	)
+
+	go func() { _, _ = Get(context.Background(), "slow") }()
+	for slowHits.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Get(context.Background(), "fast")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Get(fast): %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Get(fast) 被其他提供方的服务发现阻塞")
+	}
+}
+
+// 同一提供方的并发请求只进行一次服务发现，并得到同一个实例
+func TestGetDiscoversOnce(t *testing.T) {
+	release := make(chan struct{})
+	var hits atomic.Int32
+	issuer := newIssuer(t, release, &hits)
+	setupProviders(t, svc.OIDCProviderConfig{Name: "corp", Issuer: issuer.URL})
+
+	const n = 8
+	results := make([]*Provider, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := Get(context.Background(), "corp")
+			if err != nil {
+				t.Errorf("Get: %v", err)
+			}
+			results[i] = p
+		}()
+	}
+	for hits.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := hits.Load(); got != 1 {
+		t.Fatalf("服务发现请求 %d 次, want 1", got)
+	}
+	for i, p := range results {
+		if p == nil || p != results[0] {
+			t.Fatalf("results[%d] = %p, want %p", i, p, results[0])
+		}
+	}
+}
+
+func TestGetUnknownProvider(t *testing.T) {
+	setupProviders(t)
+	if _, err := Get(context.Background(), "missing"); err == nil {
+		t.Fatal("未配置的提供方应返回错误")
+	}
+}
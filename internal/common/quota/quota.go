@@ -0,0 +1,90 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+
+	"godir/internal/common/exterr"
+	"godir/internal/common/svc"
+	"godir/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// Effective 用户实际生效的配额，0表示不限制
+func Effective(user *model.GodirUser) int64 {
+	if user.StorageQuota != nil {
+		return *user.StorageQuota
+	}
+	return svc.Cfg().Storage.DefaultQuota
+}
+
+// Check 预检查再上传 size 字节是否会超出配额，用于签发上传凭证前
+func Check(db *gorm.DB, userID uint, size int64) error {
+	var user model.GodirUser
+	if err := db.Select("id", "storage_quota", "storage_used").First(&user, userID).Error; err != nil {
+		return fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	return exceeded(Effective(&user), user.StorageUsed, size)
+}
+
+// Reserve 原子地增加已用空间，按素材的文件大小计算，引用同一对象的多个素材分别计入，超出配额时不做修改并返回错误；需与素材记录在同一事务中调用
+func Reserve(tx *gorm.DB, userID uint, size int64) error {
+	var user model.GodirUser
+	if err := tx.Select("id", "storage_quota", "storage_used").First(&user, userID).Error; err != nil {
+		return fmt.Errorf("获取用户信息失败: %w", err)
+	}
+
+	limit := Effective(&user)
+	query := tx.Model(&model.GodirUser{}).Where("id = ?", userID)
+	if limit > 0 {
+		// 条件更新保证并发保存时不会超出配额
+		query = query.Where("storage_used + ? <= ?", size, limit)
+	}
+	result := query.UpdateColumn("storage_used", gorm.Expr("storage_used + ?", size))
+	if result.Error != nil {
+		return fmt.Errorf("更新存储用量失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return exceeded(limit, user.StorageUsed, size)
+	}
+	return nil
+}
+
+// Release 减少已用空间，彻底删除文件后调用
+func Release(tx *gorm.DB, userID uint, size int64) error {
+	return tx.Model(&model.GodirUser{}).Where("id = ?", userID).
+		UpdateColumn("storage_used", gorm.Expr("GREATEST(storage_used - ?, 0)", size)).Error
+}
+
+// Reconcile 按素材表（含回收站）重新统计所有用户的已用空间，修正计数偏差
+func Reconcile(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Exec(`UPDATE godir_user u SET storage_used = (
+		SELECT COALESCE(SUM(m.file_size), 0) FROM godir_material m WHERE m.user_id = u.id
+	)`).Error
+}
+
+func exceeded(limit, used, size int64) error {
+	if limit <= 0 || used+size <= limit {
+		return nil
+	}
+	available := limit - used
+	if available < 0 {
+		available = 0
+	}
+	return exterr.Newf(exterr.CodeQuotaExceeded, "存储空间不足：剩余%s，文件大小%s", FormatBytes(available), FormatBytes(size))
+}
+
+// FormatBytes 以易读的单位展示字节数
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,178 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"godir/internal/common/esx"
+	"godir/internal/common/logger"
+	"godir/internal/common/svc"
+	"godir/internal/model"
+
+	minioLib "github.com/minio/minio-go/v7"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	cleanupQueueKey = "material_cleanup_tasks"
+	cleanupRetryKey = "material_cleanup_retry" // ZSET，score为下次重试的时间戳
+
+	cleanupMaxAttempts = 8
+	cleanupBaseBackoff = 30 * time.Second
+	cleanupMaxBackoff  = time.Hour
+)
+
+// ThumbSuffix 缩略图对象key的后缀，与原文件key拼接得到封面key
+const ThumbSuffix = ".thumb.jpg"
+
+// CleanupTask 素材删除后清理存储的任务：删除MinIO中的原文件、封面，以及ES文档
+type CleanupTask struct {
+	MaterialID uint     `json:"material_id"`
+	Bucket     string   `json:"bucket"`
+	Keys       []string `json:"keys"`
+	Attempts   int      `json:"attempts"`
+}
+
+// PushCleanupTask 将清理任务推送到队列
+func PushCleanupTask(ctx context.Context, task *CleanupTask) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return svc.Redis().LPush(ctx, cleanupQueueKey, data).Err()
+}
+
+// MaterialObjectKeys 素材关联的全部对象key；封面可能在删除后才生成，因此总是带上默认封面key
+func MaterialObjectKeys(key, coverKey string) []string {
+	keys := []string{key, key + ThumbSuffix}
+	if coverKey != "" && coverKey != key+ThumbSuffix {
+		keys = append(keys, coverKey)
+	}
+	return keys
+}
+
+// StartCleanupWorker 启动清理任务的工作进程，失败的任务按指数退避重试
+func StartCleanupWorker() {
+	ctx := context.Background()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Logger.Error("处理清理任务发生错误", r)
+			}
+		}()
+
+		for {
+			result, err := svc.Redis().BRPop(ctx, 5*time.Second, cleanupQueueKey).Result()
+			if err != nil && err != redis.Nil {
+				logger.Logger.Error("从Redis队列获取清理任务失败", err)
+				<-time.After(5 * time.Second)
+				continue
+			}
+
+			if len(result) > 1 {
+				var task CleanupTask
+				if err := json.Unmarshal([]byte(result[1]), &task); err != nil {
+					logger.Logger.Error("解析清理任务失败", err)
+					continue
+				}
+				processCleanupTask(ctx, &task)
+			}
+		}
+	}()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Logger.Error("调度清理重试任务发生错误", r)
+			}
+		}()
+
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			requeueDueCleanupTasks(ctx)
+		}
+	}()
+}
+
+// processCleanupTask 删除对象和ES文档，任何一步失败都整体重试（删除操作是幂等的）
+func processCleanupTask(ctx context.Context, task *CleanupTask) {
+	if err := cleanupMaterial(ctx, task); err != nil {
+		task.Attempts++
+		if task.Attempts >= cleanupMaxAttempts {
+			// 超过重试次数后放弃，残留对象由孤儿对象回收兜底
+			logger.Logger.Error("清理任务多次失败，放弃重试", "material_id", task.MaterialID, "error", err)
+			return
+		}
+
+		backoff := cleanupBaseBackoff << (task.Attempts - 1)
+		if backoff > cleanupMaxBackoff {
+			backoff = cleanupMaxBackoff
+		}
+		logger.Logger.Warn("清理任务失败，稍后重试", "material_id", task.MaterialID, "attempts", task.Attempts, "error", err)
+
+		data, _ := json.Marshal(task)
+		if err := svc.Redis().ZAdd(ctx, cleanupRetryKey, redis.Z{
+			Score:  float64(time.Now().Add(backoff).Unix()),
+			Member: data,
+		}).Err(); err != nil {
+			logger.Logger.Error("写入清理重试队列失败", err)
+		}
+		return
+	}
+
+	logger.Logger.Info("素材存储清理完成", "material_id", task.MaterialID)
+}
+
+func cleanupMaterial(ctx context.Context, task *CleanupTask) error {
+	minioClient := svc.Minio()
+	for _, key := range task.Keys {
+		err := minioClient.RemoveObject(ctx, task.Bucket, key, minioLib.RemoveObjectOptions{})
+		if err != nil && minioLib.ToErrorResponse(err).Code != "NoSuchKey" {
+			return err
+		}
+	}
+
+	if task.MaterialID != 0 {
+		// 移入回收站后又被恢复的素材已重新索引，不能再删文档
+		var count int64
+		if err := svc.DB().WithContext(ctx).Model(&model.GodirMaterial{}).Where("id = ?", task.MaterialID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		if err := esx.DeleteMaterial(ctx, task.MaterialID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requeueDueCleanupTasks 把到期的重试任务移回队列；ZRem 成功才入队，避免多实例重复投递
+func requeueDueCleanupTasks(ctx context.Context) {
+	rdb := svc.Redis()
+	due, err := rdb.ZRangeByScore(ctx, cleanupRetryKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Unix(), 10),
+		Count: 100,
+	}).Result()
+	if err != nil {
+		logger.Logger.Error("读取清理重试队列失败", err)
+		return
+	}
+
+	for _, member := range due {
+		removed, err := rdb.ZRem(ctx, cleanupRetryKey, member).Result()
+		if err != nil || removed == 0 {
+			continue
+		}
+		if err := rdb.LPush(ctx, cleanupQueueKey, member).Err(); err != nil {
+			logger.Logger.Error("清理任务重新入队失败", err)
+		}
+	}
+}
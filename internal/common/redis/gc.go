@@ -0,0 +1,174 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"godir/internal/common/logger"
+	"godir/internal/common/svc"
+	"godir/internal/model"
+
+	minioLib "github.com/minio/minio-go/v7"
+)
+
+// 正式对象和客户端上传用的暂存对象都需回收；普通上传的暂存对象保存后即删除，
+// 分片上传合并后在暂存前缀下原地登记，有素材引用的不会被回收
+var orphanGCPrefixes = []string{"user/", "upload/"}
+
+const (
+	orphanGCLockKey  = "material_gc_lock"
+	orphanGCInterval = 6 * time.Hour
+	orphanGCBatch    = 500
+
+	// 只回收足够旧的对象，给上传中、待保存（分片上传意图最长24小时）的文件留出时间
+	orphanGCGrace = 48 * time.Hour
+
+	// 未完成的分片上传超过上传意图有效期（24小时）即不可能再完成，取消以释放已上传的分片
+	incompleteUploadMaxAge = 24 * time.Hour
+)
+
+// StartOrphanGC 定期回收没有对应素材记录的对象和过期未完成的分片上传，多实例部署时通过Redis锁保证同一周期只执行一次
+func StartOrphanGC() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Logger.Error("孤儿对象回收发生错误", r)
+			}
+		}()
+
+		// 启动后稍作延迟再执行第一次，避免与启动过程争抢资源
+		timer := time.NewTimer(time.Minute)
+		defer timer.Stop()
+		for range timer.C {
+			runOrphanGC(context.Background())
+			timer.Reset(orphanGCInterval)
+		}
+	}()
+}
+
+func runOrphanGC(ctx context.Context) {
+	// 锁不主动释放，过期前其他实例不会重复执行
+	ok, err := svc.Redis().SetNX(ctx, orphanGCLockKey, time.Now().Unix(), orphanGCInterval-time.Minute).Result()
+	if err != nil {
+		logger.Logger.Error("获取孤儿对象回收锁失败", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	bucket := svc.Cfg().MinIO.Bucket
+	cutoff := time.Now().Add(-orphanGCGrace)
+
+	var scanned, removed int
+	batch := make([]string, 0, orphanGCBatch)
+	flush := func() {
+		removed += removeOrphans(ctx, bucket, batch)
+		batch = batch[:0]
+	}
+
+	for _, prefix := range orphanGCPrefixes {
+		for obj := range svc.Minio().ListObjects(ctx, bucket, minioLib.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if obj.Err != nil {
+				logger.Logger.Error("列出对象失败", obj.Err)
+				return
+			}
+			scanned++
+			if obj.LastModified.After(cutoff) {
+				continue
+			}
+			batch = append(batch, obj.Key)
+			if len(batch) >= orphanGCBatch {
+				flush()
+			}
+		}
+		if len(batch) > 0 {
+			flush()
+		}
+	}
+
+	logger.Logger.Info("孤儿对象回收完成", "scanned", scanned, "removed", removed)
+
+	abortStaleMultipartUploads(ctx, bucket)
+}
+
+// abortStaleMultipartUploads 取消过期且没有上传意图的分片上传；未完成的上传不会出现在 ListObjects 中，需单独清理
+func abortStaleMultipartUploads(ctx context.Context, bucket string) {
+	core := minioLib.Core{Client: svc.Minio()}
+	cutoff := time.Now().Add(-incompleteUploadMaxAge)
+
+	var scanned, aborted int
+	for _, prefix := range orphanGCPrefixes {
+		for upload := range svc.Minio().ListIncompleteUploads(ctx, bucket, prefix, true) {
+			if upload.Err != nil {
+				logger.Logger.Error("列出未完成的分片上传失败", upload.Err)
+				return
+			}
+			scanned++
+			if upload.Initiated.After(cutoff) {
+				continue
+			}
+			if intent, err := GetUploadIntent(ctx, upload.Key); err != nil || (intent != nil && intent.UploadID == upload.UploadID) {
+				continue
+			}
+
+			err := core.AbortMultipartUpload(ctx, bucket, upload.Key, upload.UploadID)
+			if err != nil && minioLib.ToErrorResponse(err).Code != "NoSuchUpload" {
+				logger.Logger.Warn("取消分片上传失败", "key", upload.Key, "upload_id", upload.UploadID, "error", err)
+				continue
+			}
+			aborted++
+		}
+	}
+
+	logger.Logger.Info("未完成分片上传清理完成", "scanned", scanned, "aborted", aborted)
+}
+
+// removeOrphans 删除一批对象中没有被素材（含回收站）引用（原文件或封面）且没有待保存上传的对象
+func removeOrphans(ctx context.Context, bucket string, keys []string) int {
+	lookup := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lookup = append(lookup, strings.TrimSuffix(key, ThumbSuffix))
+	}
+
+	// 回收站中的素材（已软删除）仍引用对象，需一并计入
+	var files, covers []string
+	db := svc.DB().WithContext(ctx).Unscoped().Model(&model.GodirMaterial{}).Where("oss_bucket = ?", bucket)
+	if err := db.Where("oss_file_path IN ?", lookup).Pluck("oss_file_path", &files).Error; err != nil {
+		logger.Logger.Error("查询素材记录失败", err)
+		return 0
+	}
+	if err := svc.DB().WithContext(ctx).Unscoped().Model(&model.GodirMaterial{}).
+		Where("oss_bucket = ? AND cover_oss_file_path IN ?", bucket, keys).
+		Pluck("cover_oss_file_path", &covers).Error; err != nil {
+		logger.Logger.Error("查询素材记录失败", err)
+		return 0
+	}
+
+	referenced := make(map[string]bool, len(files)+len(covers))
+	for _, k := range files {
+		referenced[k] = true
+	}
+	for _, k := range covers {
+		referenced[k] = true
+	}
+
+	removed := 0
+	for i, key := range keys {
+		if referenced[key] || referenced[lookup[i]] {
+			continue
+		}
+		if intent, err := GetUploadIntent(ctx, lookup[i]); err != nil || intent != nil {
+			continue
+		}
+
+		err := svc.Minio().RemoveObject(ctx, bucket, key, minioLib.RemoveObjectOptions{})
+		if err != nil && minioLib.ToErrorResponse(err).Code != "NoSuchKey" {
+			logger.Logger.Warn("删除孤儿对象失败", "key", key, "error", err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
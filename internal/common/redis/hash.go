@@ -0,0 +1,115 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"godir/internal/common/logger"
+	"godir/internal/common/objref"
+	"godir/internal/common/svc"
+
+	minioLib "github.com/minio/minio-go/v7"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	hashQueueKey    = "material_hash_tasks"
+	hashMaxAttempts = 3
+)
+
+// HashTask 计算对象内容哈希的任务，用于保存时未同步计算哈希的大文件
+type HashTask struct {
+	MaterialID uint   `json:"material_id"`
+	Bucket     string `json:"bucket"`
+	Key        string `json:"key"`
+	Attempts   int    `json:"attempts"`
+
+	// 保存时要求复用已有对象：算出哈希后如有内容相同的文件，改为引用其对象并删除本次上传的副本
+	ReuseExisting bool `json:"reuse_existing,omitempty"`
+}
+
+// PushHashTask 将哈希计算任务推送到队列
+func PushHashTask(ctx context.Context, task *HashTask) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return svc.Redis().LPush(ctx, hashQueueKey, data).Err()
+}
+
+// StartHashWorker 启动计算内容哈希的工作进程
+func StartHashWorker() {
+	ctx := context.Background()
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Logger.Error("处理哈希任务发生错误", r)
+			}
+		}()
+
+		for {
+			result, err := svc.Redis().BRPop(ctx, 5*time.Second, hashQueueKey).Result()
+			if err != nil && err != redis.Nil {
+				logger.Logger.Error("从Redis队列获取哈希任务失败", err)
+				<-time.After(5 * time.Second)
+				continue
+			}
+
+			if len(result) > 1 {
+				var task HashTask
+				if err := json.Unmarshal([]byte(result[1]), &task); err != nil {
+					logger.Logger.Error("解析哈希任务失败", err)
+					continue
+				}
+				processHashTask(ctx, &task)
+			}
+		}
+	}()
+}
+
+// processHashTask 计算哈希并回填到引用该对象的素材；对象已被删除时放弃
+func processHashTask(ctx context.Context, task *HashTask) {
+	hash, err := objref.Hash(ctx, task.Bucket, task.Key)
+	if err == nil {
+		err = objref.SetHash(svc.DB().WithContext(ctx), task.Bucket, task.Key, hash)
+	}
+	if err != nil {
+		task.Attempts++
+		if task.Attempts >= hashMaxAttempts {
+			logger.Logger.Error("计算内容哈希多次失败，放弃重试", "material_id", task.MaterialID, "error", err)
+			return
+		}
+		logger.Logger.Warn("计算内容哈希失败，稍后重试", "material_id", task.MaterialID, "attempts", task.Attempts, "error", err)
+		if err := PushHashTask(ctx, task); err != nil {
+			logger.Logger.Error("哈希任务重新入队失败", err)
+		}
+		return
+	}
+
+	logger.Logger.Info("内容哈希计算完成", "material_id", task.MaterialID)
+
+	if task.ReuseExisting {
+		reuseExisting(ctx, task)
+	}
+}
+
+// reuseExisting 执行保存时推迟的复用，失败时保留本次上传的副本，只记录日志
+func reuseExisting(ctx context.Context, task *HashTask) {
+	reused, err := objref.Reuse(svc.DB().WithContext(ctx), task.MaterialID)
+	if err != nil {
+		logger.Logger.Error("复用已有对象失败", "material_id", task.MaterialID, "error", err)
+		return
+	}
+	if reused == nil {
+		return
+	}
+
+	logger.Logger.Info("已复用内容相同的对象", "material_id", task.MaterialID, "source_id", reused.SourceID)
+	if reused.Orphaned {
+		if err := svc.Minio().RemoveObject(ctx, reused.Bucket, reused.Key, minioLib.RemoveObjectOptions{}); err != nil {
+			logger.Logger.Warn("删除重复上传的对象失败", "key", reused.Key, "error", err)
+		}
+	}
+}
@@ -3,10 +3,14 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"godir/internal/common/logger"
@@ -15,156 +19,541 @@ import (
 
 	minioLib "github.com/minio/minio-go/v7"
 	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// 缩略图任务使用 Redis Stream + 消费组：处理完成才 XACK，进程崩溃时未确认的任务由其他消费者认领；
+// 失败的任务按指数退避放入重试集合，超过次数后进入死信流，可由管理员查看并重新投递
+const (
+	thumbnailStreamKey = "thumbnail_stream"
+	thumbnailGroup     = "thumbnail_workers"
+	thumbnailRetryKey  = "thumbnail_retry" // ZSET，score为下次重试的时间戳
+	thumbnailDeadKey   = "thumbnail_dead"  // 死信流
+	thumbnailLegacyKey = "thumbnail_tasks" // 旧版列表队列，启动时迁移其中残留的任务
+
+	thumbnailMaxAttempts = 5
+	thumbnailBaseBackoff = 30 * time.Second
+	thumbnailMaxBackoff  = 30 * time.Minute
+
+	// 已投递的任务超过任务超时时间再加上该时长仍未确认，视为消费者已崩溃，重新认领
+	thumbnailClaimGrace = 5 * time.Minute
+
+	defaultThumbnailWorkers = 4
+	defaultThumbnailTimeout = 5 * time.Minute
 )
 
 // ThumbnailTask 表示生成缩略图的任务
 type ThumbnailTask struct {
 	MaterialID  uint   `json:"material_id"`
+	UserID      uint   `json:"user_id,omitempty"` // 素材所有者，用于推送任务状态
 	Bucket      string `json:"bucket"`
 	Key         string `json:"key"`
 	ContentType string `json:"content_type"`
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// DeadThumbnailTask 死信流中的任务
+type DeadThumbnailTask struct {
+	ID       string        `json:"id"`
+	Task     ThumbnailTask `json:"task"`
+	FailedAt time.Time     `json:"failed_at"`
 }
 
 // PushThumbnailTask 将缩略图生成任务推送到队列
 func PushThumbnailTask(task *ThumbnailTask) error {
 	ctx := context.Background()
+	if err := addThumbnailTask(ctx, task); err != nil {
+		return err
+	}
+	setThumbnailStatus(ctx, task, ThumbnailQueued)
+	return nil
+}
+
+func addThumbnailTask(ctx context.Context, task *ThumbnailTask) error {
 	data, err := json.Marshal(task)
 	if err != nil {
 		return err
 	}
-
-	// 推送到Redis队列
-	return svc.Redis().LPush(ctx, "thumbnail_tasks", data).Err()
+	return svc.Redis().XAdd(ctx, &redis.XAddArgs{
+		Stream: thumbnailStreamKey,
+		Values: map[string]interface{}{"task": data},
+	}).Err()
 }
 
-// StartThumbnailWorker 启动处理缩略图任务的工作进程
-func StartThumbnailWorker() {
-	ctx := context.Background()
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				logger.Logger.Error("处理缩略图任务发生错误", r)
-			}
+// StartThumbnailWorker 启动处理缩略图任务的工作池；ctx 取消后不再领取新任务，
+// 返回的 WaitGroup 在进行中的任务全部结束后完成，供退出前等待
+func StartThumbnailWorker(ctx context.Context) *sync.WaitGroup {
+	rdb := svc.Redis()
+
+	if err := rdb.XGroupCreateMkStream(ctx, thumbnailStreamKey, thumbnailGroup, "0").Err(); err != nil &&
+		!strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		logger.Logger.Error("创建缩略图消费组失败", err)
+	}
+	migrateLegacyThumbnailTasks(ctx)
+
+	consumer := thumbnailConsumerName()
+	workers := ThumbnailWorkers()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runThumbnailWorker(ctx, consumer)
 		}()
+	}
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
 		for {
-			// 从队列中取出任务
-			result, err := svc.Redis().BRPop(ctx, 5*time.Second, "thumbnail_tasks").Result()
-			if err != nil && err != redis.Nil {
-				logger.Logger.Error("从Redis队列获取任务失败", err)
-				<-time.After(5 * time.Second) // 等待5秒后继续
-				continue
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				func() {
+					defer func() {
+						if r := recover(); r != nil {
+							logger.Logger.Error("调度缩略图重试任务发生错误", r)
+						}
+					}()
+					requeueDueThumbnailTasks(ctx)
+				}()
+			}
+		}
+	}()
+
+	logger.Logger.Info("缩略图工作池已启动", "workers", workers, "timeout", ThumbnailTimeout())
+	return &wg
+}
+
+// ThumbnailWorkers 缩略图工作池大小
+func ThumbnailWorkers() int {
+	if n := svc.Cfg().Thumbnail.Workers; n > 0 {
+		return n
+	}
+	return defaultThumbnailWorkers
+}
+
+// ThumbnailTimeout 单个缩略图任务的超时时间
+func ThumbnailTimeout() time.Duration {
+	d, err := time.ParseDuration(svc.Cfg().Thumbnail.Timeout)
+	if err != nil || d <= 0 {
+		return defaultThumbnailTimeout
+	}
+	return d
+}
+
+// runThumbnailWorker 单个工作协程：循环领取并处理任务，直到 ctx 取消
+func runThumbnailWorker(ctx context.Context, consumer string) {
+	rdb := svc.Redis()
+	for ctx.Err() == nil {
+		claimStaleThumbnailTasks(ctx, consumer)
+
+		streams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    thumbnailGroup,
+			Consumer: consumer,
+			Streams:  []string{thumbnailStreamKey, ">"},
+			Count:    1,
+			Block:    5 * time.Second,
+		}).Result()
+		if err != nil && err != redis.Nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Logger.Error("从Redis读取缩略图任务失败", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
 			}
+			continue
+		}
 
-			// 如果有任务则处理
-			if len(result) > 1 {
-				var task ThumbnailTask
-				err := json.Unmarshal([]byte(result[1]), &task)
-				if err != nil {
-					logger.Logger.Error("解析任务失败", err)
-					continue
-				}
-
-				// 处理任务
-				processThumbnailTask(&task)
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				handleThumbnailMessage(msg)
 			}
 		}
+	}
+}
+
+// handleThumbnailMessage 处理一条任务；无论成功、重试还是进入死信，都在安排好去向后才确认
+// 已领取的任务不受停止信号影响，使用独立的 context 以便退出前处理完
+func handleThumbnailMessage(msg redis.XMessage) {
+	ctx := context.Background()
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Logger.Error("处理缩略图任务发生错误", "id", msg.ID, "panic", r)
+		}
 	}()
+
+	var task ThumbnailTask
+	raw, _ := msg.Values["task"].(string)
+	if err := json.Unmarshal([]byte(raw), &task); err != nil {
+		logger.Logger.Error("解析缩略图任务失败", "id", msg.ID, "error", err)
+		ackThumbnailMessage(ctx, msg.ID)
+		return
+	}
+
+	// 旧任务没有记录所有者，从素材补齐，以便推送状态
+	if task.UserID == 0 {
+		task.UserID = thumbnailTaskOwner(ctx, task.MaterialID)
+	}
+	setThumbnailStatus(ctx, &task, ThumbnailProcessing)
+
+	if err := runThumbnailTask(&task); err != nil {
+		task.Attempts++
+		task.LastError = err.Error()
+		if task.Attempts >= thumbnailMaxAttempts {
+			logger.Logger.Error("缩略图任务多次失败，移入死信", "material_id", task.MaterialID, "error", err)
+			if err := addDeadThumbnailTask(ctx, &task); err != nil {
+				// 未能写入死信时不确认，等待重新认领
+				logger.Logger.Error("写入缩略图死信失败", err)
+				return
+			}
+			setThumbnailStatus(ctx, &task, ThumbnailFailed)
+		} else {
+			backoff := thumbnailBaseBackoff << (task.Attempts - 1)
+			if backoff > thumbnailMaxBackoff {
+				backoff = thumbnailMaxBackoff
+			}
+			logger.Logger.Warn("缩略图任务失败，稍后重试", "material_id", task.MaterialID, "attempts", task.Attempts, "error", err)
+
+			data, _ := json.Marshal(&task)
+			if err := svc.Redis().ZAdd(ctx, thumbnailRetryKey, redis.Z{
+				Score:  float64(time.Now().Add(backoff).Unix()),
+				Member: data,
+			}).Err(); err != nil {
+				logger.Logger.Error("写入缩略图重试队列失败", err)
+				return
+			}
+			setThumbnailStatus(ctx, &task, ThumbnailQueued)
+		}
+	} else if thumbnailSupported(task.ContentType) {
+		setThumbnailStatus(ctx, &task, ThumbnailDone)
+	} else {
+		setThumbnailStatus(ctx, &task, ThumbnailSkipped)
+	}
+
+	ackThumbnailMessage(ctx, msg.ID)
 }
 
-// processThumbnailTask 处理缩略图生成任务
-func processThumbnailTask(task *ThumbnailTask) {
-	logger.Logger.Info("开始处理缩略图任务", "material_id", task.MaterialID, "key", task.Key)
+func ackThumbnailMessage(ctx context.Context, id string) {
+	pipe := svc.Redis().TxPipeline()
+	pipe.XAck(ctx, thumbnailStreamKey, thumbnailGroup, id)
+	pipe.XDel(ctx, thumbnailStreamKey, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Logger.Error("确认缩略图任务失败", "id", id, "error", err)
+	}
+}
 
-	minioClient := svc.Minio()
-	if minioClient == nil {
-		logger.Logger.Error("MinIO客户端未初始化")
+// claimStaleThumbnailTasks 认领其他消费者长时间未确认的任务（通常是处理中途进程退出）并立即处理
+func claimStaleThumbnailTasks(ctx context.Context, consumer string) {
+	msgs, _, err := svc.Redis().XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   thumbnailStreamKey,
+		Group:    thumbnailGroup,
+		Consumer: consumer,
+		MinIdle:  ThumbnailTimeout() + thumbnailClaimGrace,
+		Start:    "0",
+		Count:    10,
+	}).Result()
+	if err != nil && err != redis.Nil {
+		logger.Logger.Error("认领超时的缩略图任务失败", err)
 		return
 	}
 
-	ctx := context.Background()
+	for _, msg := range msgs {
+		logger.Logger.Warn("重新处理超时未确认的缩略图任务", "id", msg.ID)
+		handleThumbnailMessage(msg)
+	}
+}
 
-	// 从MinIO下载对象到临时文件
-	obj, err := minioClient.GetObject(ctx, task.Bucket, task.Key, minioLib.GetObjectOptions{})
+// requeueDueThumbnailTasks 把到期的重试任务重新投递；ZRem 成功才投递，避免多实例重复
+func requeueDueThumbnailTasks(ctx context.Context) {
+	rdb := svc.Redis()
+	due, err := rdb.ZRangeByScore(ctx, thumbnailRetryKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(time.Now().Unix(), 10),
+		Count: 100,
+	}).Result()
 	if err != nil {
-		logger.Logger.Error("获取MinIO对象失败", err)
+		logger.Logger.Error("读取缩略图重试队列失败", err)
 		return
 	}
 
-	tmpFile, err := os.CreateTemp(os.TempDir(), "material-*")
+	for _, member := range due {
+		removed, err := rdb.ZRem(ctx, thumbnailRetryKey, member).Result()
+		if err != nil || removed == 0 {
+			continue
+		}
+		var task ThumbnailTask
+		if err := json.Unmarshal([]byte(member), &task); err != nil {
+			logger.Logger.Error("解析缩略图重试任务失败", err)
+			continue
+		}
+		if err := addThumbnailTask(ctx, &task); err != nil {
+			logger.Logger.Error("缩略图任务重新入队失败", err)
+		}
+	}
+}
+
+// migrateLegacyThumbnailTasks 将旧版列表队列中残留的任务转入 Stream
+func migrateLegacyThumbnailTasks(ctx context.Context) {
+	rdb := svc.Redis()
+	for {
+		data, err := rdb.RPop(ctx, thumbnailLegacyKey).Result()
+		if err != nil {
+			if err != redis.Nil {
+				logger.Logger.Error("迁移旧版缩略图队列失败", err)
+			}
+			return
+		}
+		if err := rdb.XAdd(ctx, &redis.XAddArgs{
+			Stream: thumbnailStreamKey,
+			Values: map[string]interface{}{"task": data},
+		}).Err(); err != nil {
+			logger.Logger.Error("迁移旧版缩略图任务失败", err)
+			_ = rdb.RPush(ctx, thumbnailLegacyKey, data).Err()
+			return
+		}
+	}
+}
+
+func addDeadThumbnailTask(ctx context.Context, task *ThumbnailTask) error {
+	data, err := json.Marshal(task)
 	if err != nil {
-		logger.Logger.Error("创建临时文件失败", err)
-		return
+		return err
 	}
+	return svc.Redis().XAdd(ctx, &redis.XAddArgs{
+		Stream: thumbnailDeadKey,
+		Values: map[string]interface{}{"task": data, "failed_at": time.Now().Unix()},
+	}).Err()
+}
 
-	_, err = io.Copy(tmpFile, obj)
+// ListDeadThumbnailTasks 按时间倒序列出死信任务，before 为上一页最后一条的ID，为空表示从最新开始
+func ListDeadThumbnailTasks(ctx context.Context, before string, count int64) ([]DeadThumbnailTask, error) {
+	end := "+"
+	if before != "" {
+		end = "(" + before
+	}
+	msgs, err := svc.Redis().XRevRangeN(ctx, thumbnailDeadKey, end, "-", count).Result()
 	if err != nil {
-		logger.Logger.Error("复制对象到临时文件失败", err)
-		_ = tmpFile.Close()
-		_ = os.Remove(tmpFile.Name())
+		return nil, err
+	}
+
+	list := make([]DeadThumbnailTask, 0, len(msgs))
+	for _, msg := range msgs {
+		list = append(list, toDeadThumbnailTask(msg))
+	}
+	return list, nil
+}
+
+// RequeueDeadThumbnailTasks 将死信任务清零重试次数后重新投递；ids 为空时投递全部，返回投递的数量
+func RequeueDeadThumbnailTasks(ctx context.Context, ids []string) (int, error) {
+	rdb := svc.Redis()
+
+	var msgs []redis.XMessage
+	if len(ids) == 0 {
+		all, err := rdb.XRange(ctx, thumbnailDeadKey, "-", "+").Result()
+		if err != nil {
+			return 0, err
+		}
+		msgs = all
+	} else {
+		for _, id := range ids {
+			found, err := rdb.XRange(ctx, thumbnailDeadKey, id, id).Result()
+			if err != nil {
+				return 0, err
+			}
+			msgs = append(msgs, found...)
+		}
+	}
+
+	requeued := 0
+	for _, msg := range msgs {
+		dead := toDeadThumbnailTask(msg)
+		// XDel 成功才投递，避免并发重复
+		removed, err := rdb.XDel(ctx, thumbnailDeadKey, msg.ID).Result()
+		if err != nil {
+			return requeued, err
+		}
+		if removed == 0 {
+			continue
+		}
+
+		task := dead.Task
+		task.Attempts = 0
+		task.LastError = ""
+		if err := addThumbnailTask(ctx, &task); err != nil {
+			_ = addDeadThumbnailTask(ctx, &dead.Task)
+			return requeued, err
+		}
+		setThumbnailStatus(ctx, &task, ThumbnailQueued)
+		requeued++
+	}
+	return requeued, nil
+}
+
+// ThumbnailQueueStats 缩略图队列各阶段的任务数
+func ThumbnailQueueStats(ctx context.Context) (pending, retrying, dead int64, err error) {
+	rdb := svc.Redis()
+	if pending, err = rdb.XLen(ctx, thumbnailStreamKey).Result(); err != nil {
+		return
+	}
+	if retrying, err = rdb.ZCard(ctx, thumbnailRetryKey).Result(); err != nil {
 		return
 	}
+	dead, err = rdb.XLen(ctx, thumbnailDeadKey).Result()
+	return
+}
 
-	_ = tmpFile.Close()
-	defer os.Remove(tmpFile.Name())
+func toDeadThumbnailTask(msg redis.XMessage) DeadThumbnailTask {
+	dead := DeadThumbnailTask{ID: msg.ID}
+	if raw, ok := msg.Values["task"].(string); ok {
+		_ = json.Unmarshal([]byte(raw), &dead.Task)
+	}
+	if raw, ok := msg.Values["failed_at"].(string); ok {
+		if ts, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			dead.FailedAt = time.Unix(ts, 0)
+		}
+	}
+	return dead
+}
+
+func thumbnailConsumerName() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// runThumbnailTask 带超时执行任务，panic 视为一次失败，交由重试流程处理
+func runThumbnailTask(task *ThumbnailTask) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ThumbnailTimeout())
+	defer cancel()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return processThumbnailTask(ctx, task)
+}
+
+// processThumbnailTask 处理缩略图生成任务，返回错误时由调用方安排重试
+func processThumbnailTask(ctx context.Context, task *ThumbnailTask) error {
+	logger.Logger.Info("开始处理缩略图任务", "material_id", task.MaterialID, "key", task.Key)
+
+	// 只有图片和视频能生成缩略图，其他类型的任务直接完成，避免反复重试后进入死信
+	if !thumbnailSupported(task.ContentType) {
+		return nil
+	}
+
+	// 素材已被彻底删除时无需生成
+	db := svc.DB().WithContext(ctx)
+	material := model.GodirMaterial{}
+	if err := db.Unscoped().First(&material, task.MaterialID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Info("素材已删除，跳过缩略图任务", "material_id", task.MaterialID)
+			return nil
+		}
+		return fmt.Errorf("查找素材记录失败: %w", err)
+	}
+
+	minioClient := svc.Minio()
+	if minioClient == nil {
+		return fmt.Errorf("MinIO客户端未初始化")
+	}
 
 	// 确保ffmpeg存在
 	if _, err := exec.LookPath("ffmpeg"); err != nil {
-		logger.Logger.Error("找不到ffmpeg命令", err)
-		return
+		return fmt.Errorf("找不到ffmpeg命令: %w", err)
+	}
+
+	// 从MinIO下载对象到临时文件
+	obj, err := minioClient.GetObject(ctx, task.Bucket, task.Key, minioLib.GetObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("获取MinIO对象失败: %w", err)
+	}
+	defer obj.Close()
+
+	tmpFile, err := os.CreateTemp(os.TempDir(), "material-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = io.Copy(tmpFile, obj)
+	_ = tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("复制对象到临时文件失败: %w", err)
 	}
 
 	// 生成缩略图
-	thumbPath := tmpFile.Name() + ".thumb.jpg"
+	thumbPath := tmpFile.Name() + ThumbSuffix
+	defer os.Remove(thumbPath)
 	var cmd *exec.Cmd
 
 	if strings.HasPrefix(task.ContentType, "image/") {
-		cmd = exec.Command("ffmpeg", "-y", "-i", tmpFile.Name(), "-vf", "scale=640:-1", "-vframes", "1", "-q:v", "2", thumbPath)
+		cmd = exec.CommandContext(ctx, "ffmpeg", "-y", "-i", tmpFile.Name(), "-vf", "scale=640:-1", "-vframes", "1", "-q:v", "2", thumbPath)
 	} else {
 		// 视频文件
-		cmd = exec.Command("ffmpeg", "-y", "-i", tmpFile.Name(), "-ss", "00:00:01", "-vframes", "1", "-q:v", "2", thumbPath)
+		cmd = exec.CommandContext(ctx, "ffmpeg", "-y", "-i", tmpFile.Name(), "-ss", "00:00:01", "-vframes", "1", "-q:v", "2", thumbPath)
 	}
 
 	out, err := cmd.CombinedOutput()
 	if err != nil {
-		logger.Logger.Error("ffmpeg执行失败", err, "output", string(out))
-		return
+		if ctx.Err() != nil {
+			return fmt.Errorf("ffmpeg执行超时: %w", ctx.Err())
+		}
+		return fmt.Errorf("ffmpeg执行失败: %w, output: %s", err, lastLine(string(out)))
 	}
 
 	// 上传缩略图到MinIO
 	tf, err := os.Open(thumbPath)
 	if err != nil {
-		logger.Logger.Error("打开缩略图文件失败", err)
-		return
+		return fmt.Errorf("打开缩略图文件失败: %w", err)
 	}
 
 	fi, _ := tf.Stat()
-	thumbKey := task.Key + ".thumb.jpg"
+	thumbKey := task.Key + ThumbSuffix
 	_, err = minioClient.PutObject(ctx, task.Bucket, thumbKey, tf, fi.Size(), minioLib.PutObjectOptions{ContentType: "image/jpeg"})
 	_ = tf.Close()
-
 	if err != nil {
-		logger.Logger.Error("上传缩略图到MinIO失败", err)
-		_ = os.Remove(thumbPath)
-		return
+		return fmt.Errorf("上传缩略图到MinIO失败: %w", err)
 	}
 
 	// 更新数据库中的封面信息
-	db := svc.DB()
-	material := model.GodirMaterial{}
-	result := db.First(&material, task.MaterialID)
+	result := db.Unscoped().Model(&material).Updates(map[string]interface{}{"cover_oss_file_path": thumbKey})
 	if result.Error != nil {
-		logger.Logger.Error("查找素材记录失败", result.Error)
-		_ = os.Remove(thumbPath)
-		return
+		return fmt.Errorf("更新素材封面信息失败: %w", result.Error)
 	}
 
-	material.CoverOssFilePath = thumbKey
-	result = db.Model(&material).Updates(map[string]interface{}{"cover_oss_file_path": material.CoverOssFilePath})
-	if result.Error != nil {
-		logger.Logger.Error("更新素材封面信息失败", result.Error)
+	logger.Logger.Info("缩略图任务处理完成", "material_id", task.MaterialID)
+	return nil
+}
+
+// thumbnailSupported 只有图片和视频能生成缩略图
+func thumbnailSupported(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "video/")
+}
+
+// thumbnailTaskOwner 查找素材所有者，找不到时返回0（不推送状态）
+func thumbnailTaskOwner(ctx context.Context, materialID uint) uint {
+	var material model.GodirMaterial
+	if err := svc.DB().WithContext(ctx).Unscoped().Select("user_id").First(&material, materialID).Error; err != nil {
+		return 0
 	}
+	return material.UserID
+}
 
-	_ = os.Remove(thumbPath)
-	logger.Logger.Info("缩略图任务处理完成", "material_id", task.MaterialID)
+// lastLine ffmpeg 输出较长，错误信息只保留最后一行
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
 }
@@ -0,0 +1,51 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"godir/internal/common/logger"
+	"godir/internal/common/quota"
+	"godir/internal/common/svc"
+)
+
+const (
+	storageReconcileLockKey  = "storage_reconcile_lock"
+	storageReconcileInterval = 6 * time.Hour
+)
+
+// StartStorageReconciler 定期按素材表重新统计用户存储用量，修正增量计数可能产生的偏差
+func StartStorageReconciler() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Logger.Error("统计存储用量发生错误", r)
+			}
+		}()
+
+		// 启动后先统计一次，保证新增字段后已有用户的用量尽快准确
+		timer := time.NewTimer(time.Minute)
+		defer timer.Stop()
+		for range timer.C {
+			reconcileStorage(context.Background())
+			timer.Reset(storageReconcileInterval)
+		}
+	}()
+}
+
+func reconcileStorage(ctx context.Context) {
+	ok, err := svc.Redis().SetNX(ctx, storageReconcileLockKey, time.Now().Unix(), storageReconcileInterval-time.Minute).Result()
+	if err != nil {
+		logger.Logger.Error("获取存储用量统计锁失败", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	if err := quota.Reconcile(ctx, svc.DB()); err != nil {
+		logger.Logger.Error("统计存储用量失败", err)
+		return
+	}
+	logger.Logger.Info("存储用量统计完成")
+}
@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"godir/internal/common/logger"
+	"godir/internal/common/svc"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 缩略图任务状态，按素材ID记录在 Redis 中，并通过 pub/sub 推送给素材所有者，多个API实例均可转发
+const (
+	ThumbnailQueued     = "queued"     // 已入队，等待处理（含失败后等待重试）
+	ThumbnailProcessing = "processing" // 处理中
+	ThumbnailDone       = "done"       // 已生成封面
+	ThumbnailSkipped    = "skipped"    // 不支持生成封面的类型
+	ThumbnailFailed     = "failed"     // 多次失败已进入死信
+	ThumbnailUnknown    = "unknown"    // 没有状态记录（已过期或任务早于状态跟踪）
+
+	thumbnailStatusKeyPrefix   = "thumbnail:status:"
+	thumbnailEventsChannelPref = "thumbnail:events:"
+	thumbnailStatusTTL         = 7 * 24 * time.Hour
+)
+
+// ThumbnailStatus 单个素材的缩略图任务状态
+type ThumbnailStatus struct {
+	MaterialID uint   `json:"materialId"`
+	State      string `json:"state"`
+	Error      string `json:"error,omitempty"` // 最近一次失败的原因
+	Attempts   int    `json:"attempts"`
+	UpdatedAt  int64  `json:"updatedAt"` // Unix 时间戳（秒）
+}
+
+// setThumbnailStatus 记录任务状态并通知素材所有者；状态只用于展示，失败时仅记录日志，不影响任务本身
+func setThumbnailStatus(ctx context.Context, task *ThumbnailTask, state string) {
+	status := ThumbnailStatus{
+		MaterialID: task.MaterialID,
+		State:      state,
+		Error:      task.LastError,
+		Attempts:   task.Attempts,
+		UpdatedAt:  time.Now().Unix(),
+	}
+
+	key := thumbnailStatusKey(task.MaterialID)
+	pipe := svc.Redis().TxPipeline()
+	pipe.HSet(ctx, key, map[string]interface{}{
+		"state":      status.State,
+		"error":      status.Error,
+		"attempts":   status.Attempts,
+		"updated_at": status.UpdatedAt,
+		"user_id":    task.UserID,
+	})
+	pipe.Expire(ctx, key, thumbnailStatusTTL)
+	if task.UserID != 0 {
+		data, _ := json.Marshal(&status)
+		pipe.Publish(ctx, thumbnailEventsChannel(task.UserID), data)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Logger.Warn("记录缩略图任务状态失败", "material_id", task.MaterialID, "state", state, "error", err)
+	}
+}
+
+// GetThumbnailStatus 读取素材的缩略图任务状态，没有记录时返回 nil
+func GetThumbnailStatus(ctx context.Context, materialID uint) (*ThumbnailStatus, error) {
+	values, err := svc.Redis().HGetAll(ctx, thumbnailStatusKey(materialID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if values["state"] == "" {
+		return nil, nil
+	}
+
+	status := &ThumbnailStatus{
+		MaterialID: materialID,
+		State:      values["state"],
+		Error:      values["error"],
+	}
+	status.Attempts, _ = strconv.Atoi(values["attempts"])
+	status.UpdatedAt, _ = strconv.ParseInt(values["updated_at"], 10, 64)
+	return status, nil
+}
+
+// SubscribeThumbnailStatus 订阅某个用户名下素材的缩略图状态变化，调用方负责 Close
+func SubscribeThumbnailStatus(ctx context.Context, userID uint) *redis.PubSub {
+	return svc.Redis().Subscribe(ctx, thumbnailEventsChannel(userID))
+}
+
+func thumbnailStatusKey(materialID uint) string {
+	return thumbnailStatusKeyPrefix + strconv.FormatUint(uint64(materialID), 10)
+}
+
+func thumbnailEventsChannel(userID uint) string {
+	return fmt.Sprintf("%s%d", thumbnailEventsChannelPref, userID)
+}
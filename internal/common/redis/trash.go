@@ -0,0 +1,151 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"godir/internal/common/logger"
+	"godir/internal/common/objref"
+	"godir/internal/common/quota"
+	"godir/internal/common/svc"
+	"godir/internal/model"
+
+	"gorm.io/gorm"
+)
+
+const (
+	trashPurgeLockKey  = "material_trash_purge_lock"
+	trashPurgeInterval = time.Hour
+	trashPurgeBatch    = 500
+
+	defaultTrashRetention = 30 * 24 * time.Hour
+)
+
+// TrashRetention 回收站保留期
+func TrashRetention() time.Duration {
+	d, err := time.ParseDuration(svc.Cfg().Trash.Retention)
+	if err != nil || d <= 0 {
+		return defaultTrashRetention
+	}
+	return d
+}
+
+// PurgeMaterials 彻底删除素材记录（及其发布记录、标签关联、分享链接），释放存储用量，并投递存储清理任务
+func PurgeMaterials(ctx context.Context, db *gorm.DB, materials []model.GodirMaterial) error {
+	if len(materials) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(materials))
+	sizes := make(map[uint]int64)
+	for _, m := range materials {
+		ids = append(ids, m.ID)
+		sizes[m.UserID] += m.FileSize
+	}
+
+	unreferenced := make(map[uint]bool, len(materials))
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("material_id IN ?", ids).Delete(&model.GodirPublishedMaterial{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("material_id IN ?", ids).Delete(&model.GodirMaterialTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("material_id IN ?", ids).Delete(&model.GodirShareLink{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&model.GodirMaterial{}).Error; err != nil {
+			return err
+		}
+		// 对象可能被内容相同的其他素材共用，引用归零后才删除
+		for _, m := range materials {
+			ok, err := objref.Release(tx, m.OssBucket, m.OssFilePath)
+			if err != nil {
+				return err
+			}
+			unreferenced[m.ID] = ok
+		}
+		for userID, size := range sizes {
+			if err := quota.Release(tx, userID, size); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// 投递失败的残留对象由孤儿对象回收兜底
+	for _, m := range materials {
+		task := &CleanupTask{MaterialID: m.ID}
+		if unreferenced[m.ID] {
+			task.Bucket = m.OssBucket
+			task.Keys = MaterialObjectKeys(m.OssFilePath, m.CoverOssFilePath)
+		}
+		err := PushCleanupTask(ctx, task)
+		if err != nil {
+			logger.Logger.Warn("推送清理任务失败", "material_id", m.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// StartTrashPurger 定期彻底删除超过保留期的回收站素材
+func StartTrashPurger() {
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Logger.Error("清理回收站发生错误", r)
+			}
+		}()
+
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purgeExpiredTrash(context.Background())
+		}
+	}()
+}
+
+func purgeExpiredTrash(ctx context.Context) {
+	ok, err := svc.Redis().SetNX(ctx, trashPurgeLockKey, time.Now().Unix(), trashPurgeInterval-time.Minute).Result()
+	if err != nil {
+		logger.Logger.Error("获取回收站清理锁失败", err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	cutoff := time.Now().Add(-TrashRetention())
+	db := svc.DB()
+
+	total := 0
+	for {
+		var materials []model.GodirMaterial
+		if err := db.WithContext(ctx).Unscoped().
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+			Limit(trashPurgeBatch).
+			Find(&materials).Error; err != nil {
+			logger.Logger.Error("查询过期回收站素材失败", err)
+			return
+		}
+		if len(materials) == 0 {
+			break
+		}
+
+		if err := PurgeMaterials(ctx, db, materials); err != nil {
+			logger.Logger.Error("彻底删除过期素材失败", err)
+			return
+		}
+		total += len(materials)
+		if len(materials) < trashPurgeBatch {
+			break
+		}
+	}
+
+	if total > 0 {
+		logger.Logger.Info("回收站清理完成", "purged", total)
+	}
+}
@@ -0,0 +1,114 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"godir/internal/common/svc"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const uploadIntentKeyPrefix = "upload:intent:"
+
+// UploadIntent 签发上传凭证时登记的待上传文件，Save 时据此校验对象归属
+type UploadIntent struct {
+	UserID      uint   `json:"user_id"`
+	Bucket      string `json:"bucket"`
+	Key         string `json:"key"`
+	FileName    string `json:"file_name"`
+	FileSize    int64  `json:"file_size"`    // 申请凭证时声明的大小
+	ContentType string `json:"content_type"` // 申请凭证时声明的类型
+	CreatedAt   int64  `json:"created_at"`
+
+	// 分片上传专用
+	UploadID string `json:"upload_id,omitempty"`
+	PartSize int64  `json:"part_size,omitempty"`
+	FolderID *uint  `json:"folder_id,omitempty"` // 完成后放入的文件夹
+}
+
+// SaveUploadIntent 登记上传意图，过期后未保存的上传将无法登记为素材
+func SaveUploadIntent(ctx context.Context, intent *UploadIntent, ttl time.Duration) error {
+	data, err := json.Marshal(intent)
+	if err != nil {
+		return err
+	}
+	return svc.Redis().Set(ctx, uploadIntentKeyPrefix+intent.Key, data, ttl).Err()
+}
+
+// GetUploadIntent 查询对象key对应的上传意图，不存在或已过期时返回nil
+func GetUploadIntent(ctx context.Context, key string) (*UploadIntent, error) {
+	data, err := svc.Redis().Get(ctx, uploadIntentKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var intent UploadIntent
+	if err := json.Unmarshal(data, &intent); err != nil {
+		return nil, err
+	}
+	return &intent, nil
+}
+
+// ConsumeUploadIntent 删除上传意图，返回false表示已被其他请求消费
+func ConsumeUploadIntent(ctx context.Context, key string) (bool, error) {
+	n, err := svc.Redis().Del(ctx, uploadIntentKeyPrefix+key).Result()
+	return n > 0, err
+}
+
+const uploadLockKeyPrefix = "upload:lock:"
+
+// LockUpload 保存上传期间加的短期锁，防止同一上传被并发保存；返回false表示已有请求在处理
+func LockUpload(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return svc.Redis().SetNX(ctx, uploadLockKeyPrefix+key, time.Now().Unix(), ttl).Result()
+}
+
+// UnlockUpload 释放保存锁
+func UnlockUpload(ctx context.Context, key string) error {
+	return svc.Redis().Del(ctx, uploadLockKeyPrefix+key).Err()
+}
+
+const (
+	uploadResultKeyPrefix = "upload:result:"
+	uploadResultTTL       = 24 * time.Hour
+)
+
+// UploadResult 分片上传登记成功后保留的结果，客户端因响应丢失而重试完成时直接返回，不重复登记
+type UploadResult struct {
+	UserID      uint   `json:"user_id"`
+	UploadID    string `json:"upload_id"`
+	MaterialID  uint   `json:"material_id"`
+	ContentHash string `json:"content_hash,omitempty"`
+	ReusedFrom  uint   `json:"reused_from,omitempty"`
+}
+
+// SaveUploadResult 记录上传的登记结果
+func SaveUploadResult(ctx context.Context, key string, result *UploadResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return svc.Redis().Set(ctx, uploadResultKeyPrefix+key, data, uploadResultTTL).Err()
+}
+
+// GetUploadResult 查询上传的登记结果，尚未登记或已过期时返回nil
+func GetUploadResult(ctx context.Context, key string) (*UploadResult, error) {
+	data, err := svc.Redis().Get(ctx, uploadResultKeyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result UploadResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
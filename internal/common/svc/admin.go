@@ -0,0 +1,43 @@
+package svc
+
+import (
+	"godir/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// BootstrapAdmins 系统中还没有任何管理员时，将配置中列出的用户ID提升为管理员，返回被提升的用户名。
+// 按用户ID而不是用户名指定，尚未注册的用户名无法被抢注后获得权限；
+// 已存在管理员后不再生效，之后的角色调整只能由管理员完成，被降级的账号也不会在重启后恢复
+func BootstrapAdmins(db *gorm.DB, userIDs []uint) ([]string, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	var promoted []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var admins int64
+		if err := tx.Model(&model.GodirUser{}).Where("role = ?", model.RoleAdmin).Count(&admins).Error; err != nil {
+			return err
+		}
+		if admins > 0 {
+			return nil
+		}
+
+		if err := tx.Model(&model.GodirUser{}).
+			Where("id IN ?", userIDs).
+			Pluck("username", &promoted).Error; err != nil {
+			return err
+		}
+		if len(promoted) == 0 {
+			return nil
+		}
+		return tx.Model(&model.GodirUser{}).
+			Where("id IN ?", userIDs).
+			Update("role", model.RoleAdmin).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
@@ -0,0 +1,72 @@
+package svc
+
+import (
+	"testing"
+
+	"godir/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newUserDB(t *testing.T, roles ...string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.GodirUser{}); err != nil {
+		t.Fatal(err)
+	}
+	for i, role := range roles {
+		if err := db.Create(&model.GodirUser{Username: string(rune('a' + i)), Role: role}).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func roleOf(t *testing.T, db *gorm.DB, id uint) string {
+	t.Helper()
+	var user model.GodirUser
+	if err := db.First(&user, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user.Role
+}
+
+func TestBootstrapAdminsPromotesWhenNoAdmin(t *testing.T) {
+	db := newUserDB(t, model.RoleEditor, model.RoleEditor)
+
+	// 不存在的ID被忽略
+	promoted, err := BootstrapAdmins(db, []uint{1, 99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(promoted) != 1 || promoted[0] != "a" {
+		t.Fatalf("promoted = %v, want [a]", promoted)
+	}
+	if role := roleOf(t, db, 1); role != model.RoleAdmin {
+		t.Fatalf("role = %s, want %s", role, model.RoleAdmin)
+	}
+	if role := roleOf(t, db, 2); role != model.RoleEditor {
+		t.Fatalf("未列出的账号 role = %s, want %s", role, model.RoleEditor)
+	}
+}
+
+// 已有管理员后不再生效：被降级的账号重启后不会恢复为管理员
+func TestBootstrapAdminsSkipsWhenAdminExists(t *testing.T) {
+	db := newUserDB(t, model.RoleEditor, model.RoleAdmin)
+
+	promoted, err := BootstrapAdmins(db, []uint{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(promoted) != 0 {
+		t.Fatalf("promoted = %v, want none", promoted)
+	}
+	if role := roleOf(t, db, 1); role != model.RoleEditor {
+		t.Fatalf("role = %s, want %s", role, model.RoleEditor)
+	}
+}
@@ -8,15 +8,22 @@ import (
 )
 
 type Config struct {
-	Env        string           `yaml:"Env"`
-	Server     ServerConfig     `yaml:"Server"`
-	DB         DBConfig         `yaml:"DB"`
-	Log        LogConfig        `yaml:"Log"`
-	JWT        JWTConfig        `yaml:"JWT"`
-	MinIO      MinIOConfig      `yaml:"MinIO"`
-	Redis      RedisConfig      `yaml:"Redis"`
-	ES         ESConfig         `yaml:"ES"`
-	VolcEngine VolcEngineConfig `yaml:"VolcEngine"`
+	Env        string               `yaml:"Env"`
+	Server     ServerConfig         `yaml:"Server"`
+	DB         DBConfig             `yaml:"DB"`
+	Log        LogConfig            `yaml:"Log"`
+	JWT        JWTConfig            `yaml:"JWT"`
+	LoginGuard LoginGuardConfig     `yaml:"LoginGuard"`
+	MinIO      MinIOConfig          `yaml:"MinIO"`
+	Redis      RedisConfig          `yaml:"Redis"`
+	ES         ESConfig             `yaml:"ES"`
+	VolcEngine VolcEngineConfig     `yaml:"VolcEngine"`
+	Mail       MailConfig           `yaml:"Mail"`
+	OIDC       []OIDCProviderConfig `yaml:"OIDC"`
+	Trash      TrashConfig          `yaml:"Trash"`
+	Storage    StorageConfig        `yaml:"Storage"`
+	Thumbnail  ThumbnailConfig      `yaml:"Thumbnail"`
+	Admin      AdminConfig          `yaml:"Admin"`
 }
 
 type ServerConfig struct {
@@ -38,8 +45,31 @@ type LogConfig struct {
 }
 
 type JWTConfig struct {
-	SecretKey string `yaml:"SecretKey"`
-	TokenExp  string `yaml:"TokenExp"` // 例如: "24h", "1h30m"
+	SecretKey       string `yaml:"SecretKey"`
+	TokenExp        string `yaml:"TokenExp"`        // 访问token有效期，例如: "15m", "1h30m"
+	RefreshTokenExp string `yaml:"RefreshTokenExp"` // 刷新token有效期，例如: "720h"
+
+	// 非对称签名：SigningKeyID 指定当前签名密钥，Keys 中的其余密钥仅用于校验，
+	// 轮换时先加入新密钥并切换 SigningKeyID，旧token过期后再移除旧密钥
+	SigningKeyID string         `yaml:"SigningKeyID"`
+	Keys         []JWTKeyConfig `yaml:"Keys"`
+}
+
+type JWTKeyConfig struct {
+	ID             string `yaml:"ID"`             // kid
+	Algorithm      string `yaml:"Algorithm"`      // RS256 或 EdDSA
+	PrivateKeyFile string `yaml:"PrivateKeyFile"` // PEM私钥，仅校验用的旧密钥可不配置
+	PublicKeyFile  string `yaml:"PublicKeyFile"`  // PEM公钥，配置了私钥时可省略
+}
+
+// LoginGuardConfig 登录防暴力破解配置，未配置的项使用默认值
+type LoginGuardConfig struct {
+	Window        string `yaml:"Window"`        // 失败次数统计的滑动窗口，默认 15m
+	MaxFailures   int    `yaml:"MaxFailures"`   // 同一用户名窗口内失败多少次后锁定，默认 5
+	IPMaxFailures int    `yaml:"IPMaxFailures"` // 同一IP窗口内失败多少次后锁定，默认 50
+	LockDuration  string `yaml:"LockDuration"`  // 锁定时长，默认 15m
+	BackoffBase   string `yaml:"BackoffBase"`   // 退避基数，第n次失败后等待 BackoffBase*2^(n-1)，默认 1s
+	BackoffMax    string `yaml:"BackoffMax"`    // 退避上限，默认 1m
 }
 
 type MinIOConfig struct {
@@ -49,6 +79,11 @@ type MinIOConfig struct {
 	UseSSL          bool   `yaml:"UseSSL"`
 	Bucket          string `yaml:"Bucket"`
 	Region          string `yaml:"Region"`
+
+	// 调用STS AssumeRole签发上传凭证的账号，留空时使用上面的主账号
+	STSAccessKeyID     string `yaml:"STSAccessKeyID"`
+	STSSecretAccessKey string `yaml:"STSSecretAccessKey"`
+	UploadTokenExp     string `yaml:"UploadTokenExp"` // 上传凭证有效期，默认15m（MinIO最短900秒）
 }
 
 type RedisConfig struct {
@@ -70,6 +105,51 @@ type VolcEngineConfig struct {
 	Endpoint        string `yaml:"Endpoint"`
 }
 
+// MailConfig SMTP邮件配置，Host为空时邮件只输出到日志
+type MailConfig struct {
+	Host        string `yaml:"Host"`
+	Port        int    `yaml:"Port"`
+	Username    string `yaml:"Username"`
+	Password    string `yaml:"Password"`
+	From        string `yaml:"From"`
+	LinkBaseURL string `yaml:"LinkBaseURL"` // 邮件中链接指向的前端地址，例如 http://localhost
+	LogOnly     bool   `yaml:"LogOnly"`     // 未配置 Host 时只把邮件写入日志（链接脱敏），仅限本地开发
+}
+
+// TrashConfig 回收站
+type TrashConfig struct {
+	Retention string `yaml:"Retention"` // 删除后保留多久再彻底清除，默认720h（30天）
+}
+
+// StorageConfig 存储配额与去重
+type StorageConfig struct {
+	DefaultQuota  int64 `yaml:"DefaultQuota"`  // 每个用户默认可用字节数，0表示不限制；可在用户上单独覆盖
+	HashSyncLimit int64 `yaml:"HashSyncLimit"` // 保存时同步计算内容哈希的文件大小上限，超过的由后台计算，默认64MiB
+	ZipMaxSize    int64 `yaml:"ZipMaxSize"`    // 打包下载的文件总大小上限，默认2GiB
+}
+
+// ThumbnailConfig 缩略图生成
+type ThumbnailConfig struct {
+	Workers int    `yaml:"Workers"` // 并发处理的任务数，默认4
+	Timeout string `yaml:"Timeout"` // 单个任务（下载+ffmpeg+上传）的超时时间，默认5m
+}
+
+// AdminConfig 管理员引导：角色只能由管理员调整，首个管理员通过配置指定
+type AdminConfig struct {
+	UserIDs []uint `yaml:"UserIDs"` // 系统中还没有管理员时，启动时提升为管理员的账号ID；已有管理员后不再生效
+}
+
+// OIDCProviderConfig 一个OpenID Connect身份提供方
+type OIDCProviderConfig struct {
+	Name            string   `yaml:"Name"`   // 提供方标识，例如 corp
+	Issuer          string   `yaml:"Issuer"` // 须与服务发现文档中的 issuer 完全一致
+	ClientID        string   `yaml:"ClientID"`
+	ClientSecret    string   `yaml:"ClientSecret"`
+	RedirectURL     string   `yaml:"RedirectURL"`     // 前端回调页地址，需在IdP中登记
+	Scopes          []string `yaml:"Scopes"`          // 默认 openid profile email
+	AutoLinkByEmail bool     `yaml:"AutoLinkByEmail"` // 首次登录时按已验证邮箱关联已有账号，仅对可信IdP开启
+}
+
 func LoadConfig(configFile string) (*Config, error) {
 	// 优先级：显式参数 > 环境变量 CONFIG_FILE > 默认 config/local.yml
 	if configFile == "" {
@@ -20,7 +20,8 @@ func InitDB(c *Config) (*gorm.DB, error) {
 	// 连接数据库
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local", c.DB.Username, c.DB.Password, c.DB.Host, c.DB.Port, c.DB.Database)
 	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:         logger.Default.LogMode(logger.Info),
+		TranslateError: true, // 唯一索引冲突统一返回 gorm.ErrDuplicatedKey
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect database: %w", err)
@@ -34,6 +35,15 @@ func InitDB(c *Config) (*gorm.DB, error) {
 		ShardingSuffixs:     ShardingSuffixs,
 	}, "user"))
 
+	// 素材-标签关联表使用自定义模型（带创建时间）
+	if err := db.SetupJoinTable(&model.GodirMaterial{}, "Tags", &model.GodirMaterialTag{}); err != nil {
+		return nil, fmt.Errorf("failed to setup join table: %w", err)
+	}
+
+	if err := migrateUserEmail(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate user email: %w", err)
+	}
+
 	db.AutoMigrate(
 		&model.User{},
 		&model.GodirUser{},
@@ -41,6 +51,18 @@ func InitDB(c *Config) (*gorm.DB, error) {
 		&model.GodirPublishedMaterial{},
 		&model.GodirPublishedLike{},
 		&model.GodirAiApp{},
+		&model.GodirRefreshToken{},
+		&model.GodirAPIKey{},
+		&model.GodirRecoveryCode{},
+		&model.GodirAuditLog{},
+		&model.GodirUserToken{},
+		&model.GodirUserIdentity{},
+		&model.GodirSession{},
+		&model.GodirFolder{},
+		&model.GodirTag{},
+		&model.GodirMaterialTag{},
+		&model.GodirObject{},
+		&model.GodirShareLink{},
 	)
 
 	// 获取底层sql.DB对象进行连接池配置
@@ -57,6 +79,22 @@ func InitDB(c *Config) (*gorm.DB, error) {
 	return db, nil
 }
 
+// migrateUserEmail 用户邮箱改为唯一索引前的数据迁移：空字符串改为NULL，删除旧的普通索引。
+// 已有重复的非空邮箱时唯一索引无法创建，需先人工处理
+func migrateUserEmail(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&model.GodirUser{}) {
+		return nil
+	}
+	if err := db.Unscoped().Model(&model.GodirUser{}).Where("email = ?", "").Update("email", nil).Error; err != nil {
+		return err
+	}
+	if m.HasIndex(&model.GodirUser{}, "idx_godir_user_email") {
+		return m.DropIndex(&model.GodirUser{}, "idx_godir_user_email")
+	}
+	return nil
+}
+
 // Close 关闭数据库连接
 func Close() error {
 	if svc.DB != nil {
@@ -0,0 +1,67 @@
+package svc
+
+import (
+	"errors"
+	"testing"
+
+	"godir/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// legacyUser 邮箱改为唯一索引之前的表结构
+type legacyUser struct {
+	gorm.Model
+	Username string `gorm:"size:128;not null"`
+	Password string `gorm:"size:255;not null"`
+	Role     string `gorm:"size:32;not null;default:editor"`
+	Email    string `gorm:"size:255;index:idx_godir_user_email"`
+}
+
+func (legacyUser) TableName() string {
+	return "godir_user"
+}
+
+// 旧数据中的空邮箱迁移为NULL后，多个未设置邮箱的账号可以共存，重复的邮箱被唯一索引拦截
+func TestMigrateUserEmail(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&legacyUser{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range []legacyUser{{Username: "a"}, {Username: "b"}, {Username: "c", Email: "c@example.com"}} {
+		if err := db.Create(&u).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := migrateUserEmail(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.GodirUser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var empty int64
+	if err := db.Model(&model.GodirUser{}).Where("email IS NULL").Count(&empty).Error; err != nil {
+		t.Fatal(err)
+	}
+	if empty != 2 {
+		t.Fatalf("NULL邮箱 = %d, want 2", empty)
+	}
+
+	if err := db.Create(&model.GodirUser{Username: "d"}).Error; err != nil {
+		t.Fatalf("未设置邮箱的账号: %v", err)
+	}
+	err = db.Create(&model.GodirUser{Username: "e", Email: model.NullEmail("c@example.com")}).Error
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Fatalf("重复邮箱 err = %v, want ErrDuplicatedKey", err)
+	}
+}
@@ -0,0 +1,25 @@
+package svc
+
+import (
+	"fmt"
+
+	"godir/internal/common/mailer"
+)
+
+// InitMailer 初始化邮件发送器；未配置SMTP时必须显式开启 Mail.LogOnly 才允许启动（只写日志，仅限本地开发），
+// 避免生产环境因漏配而静默丢失邮件
+func InitMailer(cfg *Config) (mailer.Mailer, error) {
+	if cfg.Mail.Host == "" {
+		if !cfg.Mail.LogOnly {
+			return nil, fmt.Errorf("未配置邮件服务：请设置 Mail.Host，本地开发可设置 Mail.LogOnly: true")
+		}
+		return mailer.LogMailer{}, nil
+	}
+	return &mailer.SMTPMailer{
+		Host:     cfg.Mail.Host,
+		Port:     cfg.Mail.Port,
+		Username: cfg.Mail.Username,
+		Password: cfg.Mail.Password,
+		From:     cfg.Mail.From,
+	}, nil
+}
@@ -1,6 +1,8 @@
 package svc
 
 import (
+	"godir/internal/common/mailer"
+
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/minio/minio-go/v7"
 	"github.com/redis/go-redis/v9"
@@ -11,11 +13,12 @@ import (
 var svc = new(ServiceContext)
 
 type ServiceContext struct {
-	Cfg   *Config
-	DB    *gorm.DB
-	Redis *redis.Client
-	Minio *minio.Client
-	ES    *elasticsearch.Client
+	Cfg    *Config
+	DB     *gorm.DB
+	Redis  *redis.Client
+	Minio  *minio.Client
+	ES     *elasticsearch.Client
+	Mailer mailer.Mailer
 }
 
 func Init(configFile string) (*ServiceContext, error) {
@@ -46,6 +49,11 @@ func Init(configFile string) (*ServiceContext, error) {
 	if err != nil {
 		return nil, err
 	}
+
+	svc.Mailer, err = InitMailer(svc.Cfg)
+	if err != nil {
+		return nil, err
+	}
 	return svc, nil
 }
 
@@ -74,3 +82,8 @@ func Minio() *minio.Client {
 func ES() *elasticsearch.Client {
 	return svc.ES
 }
+
+// Mailer 返回邮件发送器
+func Mailer() mailer.Mailer {
+	return svc.Mailer
+}
@@ -0,0 +1,133 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B的SHA-1测试密钥 "12345678901234567890"（base32编码）
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 附录B的SHA-1测试向量，验证码取8位结果的后6位
+func TestValidateRFC6238Vectors(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		at := time.Unix(tt.unix, 0)
+		step, ok := Validate(rfcSecret, tt.code, at)
+		if !ok {
+			t.Errorf("Validate(%d, %s) 未通过", tt.unix, tt.code)
+			continue
+		}
+		if want := tt.unix / period; step != want {
+			t.Errorf("Validate(%d, %s) step = %d, want %d", tt.unix, tt.code, step, want)
+		}
+	}
+}
+
+func TestValidateDriftWindow(t *testing.T) {
+	key, _ := b32.DecodeString(rfcSecret)
+	const step = 1000000
+	code := generate(key, step)
+	start := int64(step * period) // 该时间步的第一秒
+
+	tests := []struct {
+		name string
+		unix int64
+		ok   bool
+	}{
+		{"当前时间步开始", start, true},
+		{"当前时间步结束", start + period - 1, true},
+		{"上一时间步开始（时钟快1步）", start - period, true},
+		{"超出上一时间步", start - period - 1, false},
+		{"下一时间步结束（时钟慢1步）", start + 2*period - 1, true},
+		{"超出下一时间步", start + 2*period, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Validate(rfcSecret, code, time.Unix(tt.unix, 0))
+		if ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		// 偏差窗口内匹配到的始终是验证码所属的时间步
+		if ok && got != step {
+			t.Errorf("%s: step = %d, want %d", tt.name, got, step)
+		}
+	}
+}
+
+// 调用方按返回的时间步记录已使用的验证码；同一验证码在偏差窗口内重放时返回相同的时间步，从而被拦截
+func TestValidateReplayReturnsSameStep(t *testing.T) {
+	key, _ := b32.DecodeString(rfcSecret)
+	const step = 2000000
+	code := generate(key, step)
+
+	first, ok := Validate(rfcSecret, code, time.Unix(step*period, 0))
+	if !ok {
+		t.Fatal("首次校验未通过")
+	}
+	replay, ok := Validate(rfcSecret, code, time.Unix((step+1)*period+10, 0))
+	if !ok {
+		t.Fatal("窗口内重放应能匹配到原时间步")
+	}
+	if replay != first {
+		t.Fatalf("重放返回的时间步 = %d, want %d", replay, first)
+	}
+
+	next, ok := Validate(rfcSecret, generate(key, step+1), time.Unix((step+1)*period, 0))
+	if !ok || next == first {
+		t.Fatalf("下一时间步的新验证码 step = %d, ok = %v，应与 %d 不同", next, ok, first)
+	}
+}
+
+func TestValidateRejectsMalformedInput(t *testing.T) {
+	at := time.Unix(59, 0)
+
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		ok     bool
+	}{
+		{"前后空格", rfcSecret, " 287082 ", true},
+		{"小写密钥", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", true},
+		{"位数不足", rfcSecret, "28708", false},
+		{"8位验证码", rfcSecret, "94287082", false},
+		{"错误验证码", rfcSecret, "287083", false},
+		{"非法密钥", "not-base32!", "287082", false},
+		{"空验证码", rfcSecret, "", false},
+	}
+
+	for _, tt := range tests {
+		if _, ok := Validate(tt.secret, tt.code, at); ok != tt.ok {
+			t.Errorf("%s: ok = %v, want %v", tt.name, ok, tt.ok)
+		}
+	}
+}
+
+func TestGenerateSecretRoundTrip(t *testing.T) {
+	secret := GenerateSecret()
+	key, err := b32.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("密钥无法解码: %v", err)
+	}
+	if len(key) != 20 {
+		t.Fatalf("密钥长度 = %d 字节, want 20", len(key))
+	}
+
+	now := time.Now()
+	if _, ok := Validate(secret, generate(key, now.Unix()/period), now); !ok {
+		t.Fatal("使用生成的密钥计算的验证码未通过校验")
+	}
+}
@@ -0,0 +1,82 @@
+package mimeutil
+
+import (
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// SniffLen 内容嗅探需要读取的文件头长度
+const SniffLen = 3072
+
+// 素材分类
+const (
+	FamilyImage    = "image"
+	FamilyVideo    = "video"
+	FamilyAudio    = "audio"
+	FamilyDocument = "document"
+	FamilyOther    = "other"
+)
+
+// Normalize 去掉参数并转为小写，例如 "Text/Plain; charset=utf-8" -> "text/plain"
+func Normalize(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt, _, _ = strings.Cut(contentType, ";")
+	}
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// Sniff 根据文件头的魔数识别真实类型
+func Sniff(r io.Reader) (string, error) {
+	m, err := mimetype.DetectReader(io.LimitReader(r, SniffLen))
+	if err != nil {
+		return "", err
+	}
+	return Normalize(m.String()), nil
+}
+
+// Family 将内容类型归入素材分类
+func Family(contentType string) string {
+	ct := Normalize(contentType)
+	switch {
+	case strings.HasPrefix(ct, "image/"):
+		return FamilyImage
+	case strings.HasPrefix(ct, "video/"):
+		return FamilyVideo
+	case strings.HasPrefix(ct, "audio/"):
+		return FamilyAudio
+	case strings.HasPrefix(ct, "text/"), ct == "application/pdf", ct == "application/json",
+		strings.HasPrefix(ct, "application/msword"), strings.HasPrefix(ct, "application/vnd."):
+		return FamilyDocument
+	}
+	return FamilyOther
+}
+
+// Compatible 判断客户端声明的类型与嗅探结果是否一致
+// 声明为空或 octet-stream 时不做限制；图片/音视频必须与嗅探结果同类，其余类型只要不冒充/被冒充为图片音视频即可
+func Compatible(declared, detected string) bool {
+	declared, detected = Normalize(declared), Normalize(detected)
+	if declared == "" || declared == "application/octet-stream" || declared == detected {
+		return true
+	}
+
+	// 嗅探结果是声明类型的子类型，例如声明 application/zip、实际为 docx
+	for m := mimetype.Lookup(detected); m != nil; m = m.Parent() {
+		if m.Is(declared) {
+			return true
+		}
+	}
+
+	df, tf := Family(declared), Family(detected)
+	if isMedia(df) || isMedia(tf) {
+		return df == tf
+	}
+	return true
+}
+
+func isMedia(family string) bool {
+	return family == FamilyImage || family == FamilyVideo || family == FamilyAudio
+}
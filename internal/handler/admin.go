@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"godir/internal/common/ginx"
+	"godir/internal/handler/admin"
+	"godir/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterAdminRouter(r *gin.Engine) {
+	// 仅管理员可访问的路由组
+	protected := r.Group("/admin")
+	protected.Use(ginx.AuthMiddleware(), ginx.RejectAPIKey(), ginx.RequireRole(model.RoleAdmin))
+	{
+		protected.POST("/ai-app/create", ginx.WrapHandlerObj((*admin.Admin).CreateAiApp))
+		protected.POST("/ai-app/update", ginx.WrapHandlerObj((*admin.Admin).UpdateAiApp))
+		protected.POST("/ai-app/delete", ginx.WrapHandlerObj((*admin.Admin).DeleteAiApp))
+		protected.POST("/published/delete", ginx.WrapHandlerObj((*admin.Admin).DeletePublished))
+		protected.POST("/user/role", ginx.WrapHandlerObj((*admin.Admin).SetUserRole))
+		protected.POST("/user/quota", ginx.WrapHandlerObj((*admin.Admin).SetUserQuota))
+		protected.GET("/thumbnail/dead", ginx.WrapHandlerObj((*admin.Admin).ListDeadThumbnails))
+		protected.POST("/thumbnail/requeue", ginx.WrapHandlerObj((*admin.Admin).RequeueDeadThumbnails))
+	}
+}
@@ -0,0 +1,169 @@
+package admin
+
+import (
+	"fmt"
+
+	"godir/internal/common/ginx"
+	"godir/internal/common/jwt"
+	"godir/internal/common/quota"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Admin struct {
+	ginx.BaseHandler
+}
+
+func (h *Admin) New() ginx.BaseHandlerInterface {
+	return new(Admin)
+}
+
+// CreateAiApp 新增AI应用
+func (h *Admin) CreateAiApp(c *gin.Context, req *types.AdminAiAppCreateReq) (*types.AdminAiAppResp, error) {
+	app := model.GodirAiApp{
+		Name:  req.Name,
+		AppID: req.AppID,
+		Desc:  req.Desc,
+		Icon:  req.Icon,
+		Cover: req.Cover,
+	}
+	if err := h.DB.Create(&app).Error; err != nil {
+		return nil, fmt.Errorf("创建AI应用失败: %w", err)
+	}
+
+	return &types.AdminAiAppResp{App: toAiAppInfo(&app)}, nil
+}
+
+// UpdateAiApp 修改AI应用，仅更新非空字段
+func (h *Admin) UpdateAiApp(c *gin.Context, req *types.AdminAiAppUpdateReq) (*types.AdminAiAppResp, error) {
+	var app model.GodirAiApp
+	if err := h.DB.First(&app, req.ID).Error; err != nil {
+		return nil, fmt.Errorf("AI应用不存在: %w", err)
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.AppID != "" {
+		updates["app_id"] = req.AppID
+	}
+	if req.Desc != "" {
+		updates["desc"] = req.Desc
+	}
+	if req.Icon != "" {
+		updates["icon"] = req.Icon
+	}
+	if req.Cover != "" {
+		updates["cover"] = req.Cover
+	}
+
+	if len(updates) > 0 {
+		if err := h.DB.Model(&app).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("更新AI应用失败: %w", err)
+		}
+	}
+
+	return &types.AdminAiAppResp{App: toAiAppInfo(&app)}, nil
+}
+
+// DeleteAiApp 删除AI应用
+func (h *Admin) DeleteAiApp(c *gin.Context, req *types.AdminAiAppDeleteReq) (*types.AdminDeleteResp, error) {
+	if len(req.Ids) == 0 {
+		return nil, fmt.Errorf("请选择要删除的AI应用")
+	}
+
+	result := h.DB.Where("id IN (?)", req.Ids).Delete(&model.GodirAiApp{})
+	if result.Error != nil {
+		return nil, fmt.Errorf("删除AI应用失败: %w", result.Error)
+	}
+
+	return &types.AdminDeleteResp{Deleted: result.RowsAffected}, nil
+}
+
+// DeletePublished 下架发布内容（同时删除点赞记录）
+func (h *Admin) DeletePublished(c *gin.Context, req *types.AdminPublishedDeleteReq) (*types.AdminDeleteResp, error) {
+	if len(req.Ids) == 0 {
+		return nil, fmt.Errorf("请选择要下架的发布")
+	}
+
+	var deleted int64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id IN (?)", req.Ids).Delete(&model.GodirPublishedMaterial{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+
+		return tx.Where("published_id IN (?)", req.Ids).Delete(&model.GodirPublishedLike{}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("下架发布失败: %w", err)
+	}
+
+	h.Log.Infof("管理员下架发布内容: %v", req.Ids)
+	return &types.AdminDeleteResp{Deleted: deleted}, nil
+}
+
+// SetUserRole 调整用户角色，调整后该用户已签发的token全部失效
+func (h *Admin) SetUserRole(c *gin.Context, req *types.AdminSetUserRoleReq) (*types.AdminSetUserRoleResp, error) {
+	if !model.IsValidRole(req.Role) {
+		return nil, fmt.Errorf("不支持的角色: %s", req.Role)
+	}
+
+	result := h.DB.Model(&model.GodirUser{}).Where("id = ?", req.UserID).Update("role", req.Role)
+	if result.Error != nil {
+		return nil, fmt.Errorf("更新用户角色失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		h.DB.Model(&model.GodirUser{}).Where("id = ?", req.UserID).Count(&count)
+		if count == 0 {
+			return nil, fmt.Errorf("用户不存在")
+		}
+	}
+
+	// 让旧角色的token失效，用户重新登录后拿到新角色
+	if _, err := jwt.RevokeAll(h.Ctx, req.UserID); err != nil {
+		h.Log.Errorf("吊销用户token失败: %v", err)
+	}
+
+	return &types.AdminSetUserRoleResp{UserID: req.UserID, Role: req.Role}, nil
+}
+
+// SetUserQuota 调整用户存储配额，已超出的用户不能再上传但已有文件不受影响
+func (h *Admin) SetUserQuota(c *gin.Context, req *types.AdminSetUserQuotaReq) (*types.AdminSetUserQuotaResp, error) {
+	var user model.GodirUser
+	if err := h.DB.First(&user, req.UserID).Error; err != nil {
+		return nil, fmt.Errorf("用户不存在")
+	}
+
+	var value any // nil 写入 NULL，恢复默认配额
+	if req.Quota != nil {
+		value = *req.Quota
+	}
+	if err := h.DB.Model(&user).Update("storage_quota", value).Error; err != nil {
+		return nil, fmt.Errorf("更新存储配额失败: %w", err)
+	}
+	user.StorageQuota = req.Quota
+
+	return &types.AdminSetUserQuotaResp{
+		UserID: user.ID,
+		Quota:  quota.Effective(&user),
+		Used:   user.StorageUsed,
+	}, nil
+}
+
+func toAiAppInfo(app *model.GodirAiApp) types.AiAppInfo {
+	return types.AiAppInfo{
+		ID:    app.ID,
+		Name:  app.Name,
+		AppID: app.AppID,
+		Desc:  app.Desc,
+		Icon:  app.Icon,
+		Cover: app.Cover,
+	}
+}
@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"fmt"
+
+	"godir/internal/common/redis"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListDeadThumbnails 查看多次失败后进入死信的缩略图任务及各队列的任务数
+func (h *Admin) ListDeadThumbnails(c *gin.Context, req *types.AdminThumbnailDeadReq) (*types.AdminThumbnailDeadResp, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	pending, retrying, dead, err := redis.ThumbnailQueueStats(h.Ctx)
+	if err != nil {
+		return nil, fmt.Errorf("查询缩略图队列失败: %w", err)
+	}
+
+	tasks, err := redis.ListDeadThumbnailTasks(h.Ctx, req.Before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询缩略图死信失败: %w", err)
+	}
+
+	list := make([]types.AdminThumbnailDeadItem, 0, len(tasks))
+	for _, t := range tasks {
+		list = append(list, types.AdminThumbnailDeadItem{
+			ID:          t.ID,
+			MaterialID:  t.Task.MaterialID,
+			Bucket:      t.Task.Bucket,
+			Key:         t.Task.Key,
+			ContentType: t.Task.ContentType,
+			Attempts:    t.Task.Attempts,
+			LastError:   t.Task.LastError,
+			FailedAt:    t.FailedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	nextBefore := ""
+	if int64(len(tasks)) == limit {
+		nextBefore = tasks[len(tasks)-1].ID
+	}
+
+	return &types.AdminThumbnailDeadResp{
+		Pending:    pending,
+		Retrying:   retrying,
+		Dead:       dead,
+		List:       list,
+		NextBefore: nextBefore,
+	}, nil
+}
+
+// RequeueDeadThumbnails 将死信任务重新投递，重试次数清零
+func (h *Admin) RequeueDeadThumbnails(c *gin.Context, req *types.AdminThumbnailRequeueReq) (*types.AdminThumbnailRequeueResp, error) {
+	if !req.All && len(req.Ids) == 0 {
+		return nil, fmt.Errorf("请选择要重新投递的任务")
+	}
+
+	ids := req.Ids
+	if req.All {
+		ids = nil
+	}
+	requeued, err := redis.RequeueDeadThumbnailTasks(h.Ctx, ids)
+	if err != nil {
+		h.Log.Errorf("重新投递缩略图任务失败: %v", err)
+		return nil, fmt.Errorf("重新投递失败，已投递%d个", requeued)
+	}
+
+	return &types.AdminThumbnailRequeueResp{Requeued: requeued}, nil
+}
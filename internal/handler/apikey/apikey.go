@@ -0,0 +1,146 @@
+package apikey
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"godir/internal/common/apikey"
+	"godir/internal/common/ginx"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKey struct {
+	ginx.BaseHandler
+}
+
+func (h *APIKey) New() ginx.BaseHandlerInterface {
+	return new(APIKey)
+}
+
+// Create 创建API Key，明文只在本次响应中返回
+func (h *APIKey) Create(c *gin.Context, req *types.APIKeyCreateReq) (*types.APIKeyCreateResp, error) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		return nil, fmt.Errorf("未登录")
+	}
+
+	userIDUint, ok := userID.(uint)
+	if !ok {
+		return nil, fmt.Errorf("用户ID格式错误")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("名称不能为空")
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, fmt.Errorf("请至少选择一个权限范围")
+	}
+	seen := make(map[string]bool, len(req.Scopes))
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		if !apikey.IsValidScope(s) {
+			return nil, fmt.Errorf("不支持的权限范围: %s", s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+
+	if req.ExpiresInDays < 0 {
+		return nil, fmt.Errorf("有效期不能为负数")
+	}
+
+	key, display, hash := apikey.Generate()
+	k := model.GodirAPIKey{
+		UserID:  userIDUint,
+		Name:    name,
+		Prefix:  display,
+		KeyHash: hash,
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		k.ExpiresAt = &expiresAt
+	}
+
+	if err := h.DB.Create(&k).Error; err != nil {
+		return nil, fmt.Errorf("创建API Key失败: %w", err)
+	}
+
+	return &types.APIKeyCreateResp{
+		APIKeyInfo: toAPIKeyInfo(&k),
+		Key:        key,
+	}, nil
+}
+
+// List 列出当前用户未吊销的API Key
+func (h *APIKey) List(c *gin.Context, req *types.APIKeyListReq) (*types.APIKeyListResp, error) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		return nil, fmt.Errorf("未登录")
+	}
+
+	userIDUint, ok := userID.(uint)
+	if !ok {
+		return nil, fmt.Errorf("用户ID格式错误")
+	}
+
+	var keys []model.GodirAPIKey
+	if err := h.DB.Where("user_id = ?", userIDUint).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("查询API Key失败: %w", err)
+	}
+
+	list := make([]types.APIKeyInfo, 0, len(keys))
+	for i := range keys {
+		list = append(list, toAPIKeyInfo(&keys[i]))
+	}
+
+	return &types.APIKeyListResp{Keys: list}, nil
+}
+
+// Revoke 吊销API Key
+func (h *APIKey) Revoke(c *gin.Context, req *types.APIKeyRevokeReq) (*types.APIKeyRevokeResp, error) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		return nil, fmt.Errorf("未登录")
+	}
+
+	userIDUint, ok := userID.(uint)
+	if !ok {
+		return nil, fmt.Errorf("用户ID格式错误")
+	}
+
+	result := h.DB.Where("id = ? AND user_id = ?", req.ID, userIDUint).Delete(&model.GodirAPIKey{})
+	if result.Error != nil {
+		return nil, fmt.Errorf("吊销API Key失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("API Key不存在或无权限操作")
+	}
+
+	return &types.APIKeyRevokeResp{Message: "已吊销"}, nil
+}
+
+func toAPIKeyInfo(k *model.GodirAPIKey) types.APIKeyInfo {
+	info := types.APIKeyInfo{
+		ID:        k.ID,
+		Name:      k.Name,
+		Prefix:    k.Prefix,
+		Scopes:    k.ScopeList(),
+		CreatedAt: k.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if k.ExpiresAt != nil {
+		info.ExpiresAt = k.ExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	if k.LastUsedAt != nil {
+		info.LastUsedAt = k.LastUsedAt.Format("2006-01-02 15:04:05")
+	}
+	return info
+}
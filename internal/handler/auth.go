@@ -1,24 +1,52 @@
 package handler
 
 import (
+	"net/http"
+
 	"godir/internal/common/ginx"
+	"godir/internal/common/jwt"
 	"godir/internal/handler/auth"
 
 	"github.com/gin-gonic/gin"
 )
 
 func RegisterAuthRouter(r *gin.Engine) {
+	// 发布token校验公钥，返回标准JWKS格式而不是统一响应结构
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, jwt.JWKS())
+	})
+
 	// 不需要认证的路由组
 	public := r.Group("/auth")
 	{
 		public.POST("/register", ginx.WrapHandlerObj((*auth.Auth).Register))
 		public.POST("/login", ginx.WrapHandlerObj((*auth.Auth).Login))
+		public.POST("/refresh", ginx.WrapHandlerObj((*auth.Auth).Refresh))
+		public.POST("/login/2fa", ginx.WrapHandlerObj((*auth.Auth).Login2FA))
+		public.POST("/verify-email", ginx.WrapHandlerObj((*auth.Auth).VerifyEmail))
+		public.POST("/email/confirm", ginx.WrapHandlerObj((*auth.Auth).ConfirmEmailChange))
+		public.POST("/forgot-password", ginx.WrapHandlerObj((*auth.Auth).ForgotPassword))
+		public.POST("/reset-password", ginx.WrapHandlerObj((*auth.Auth).ResetPassword))
+		public.GET("/oidc/providers", ginx.WrapHandlerObj((*auth.Auth).OIDCProviders))
+		public.POST("/oidc/login", ginx.WrapHandlerObj((*auth.Auth).OIDCLogin))
+		public.POST("/oidc/callback", ginx.WrapHandlerObj((*auth.Auth).OIDCCallback))
 	}
 
 	// 需要认证的路由组
 	protected := r.Group("/auth")
-	protected.Use(ginx.AuthMiddleware())
+	protected.Use(ginx.AuthMiddleware(), ginx.RejectAPIKey())
 	{
 		protected.POST("/logout", ginx.WrapHandlerObj((*auth.Auth).Logout))
+		protected.POST("/logout-all", ginx.WrapHandlerObj((*auth.Auth).LogoutAll))
+		protected.POST("/resend-verification", ginx.WrapHandlerObj((*auth.Auth).ResendVerification))
+		protected.POST("/email/change", ginx.WrapHandlerObj((*auth.Auth).ChangeEmail))
+		protected.POST("/2fa/enroll", ginx.WrapHandlerObj((*auth.Auth).EnrollTwoFactor))
+		protected.POST("/2fa/confirm", ginx.WrapHandlerObj((*auth.Auth).ConfirmTwoFactor))
+		protected.POST("/2fa/disable", ginx.WrapHandlerObj((*auth.Auth).DisableTwoFactor))
+		protected.POST("/oidc/link", ginx.WrapHandlerObj((*auth.Auth).OIDCLink))
+		protected.POST("/oidc/link/callback", ginx.WrapHandlerObj((*auth.Auth).OIDCLinkCallback))
+		protected.GET("/identities", ginx.WrapHandlerObj((*auth.Auth).ListIdentities))
+		protected.POST("/identities/unlink", ginx.WrapHandlerObj((*auth.Auth).UnlinkIdentity))
 	}
 }
@@ -0,0 +1,344 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"godir/internal/common/jwt"
+	"godir/internal/common/mailer"
+	"godir/internal/common/svc"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	verifyEmailTokenTTL   = 24 * time.Hour
+	resetPasswordTokenTTL = 30 * time.Minute
+	changeEmailTokenTTL   = 24 * time.Hour
+
+	// 同一邮箱两次发送之间的最小间隔
+	mailCooldownKeyPrefix = "auth:mail:cooldown:"
+	mailCooldown          = time.Minute
+)
+
+// VerifyEmail 使用邮件中的token完成邮箱验证
+func (h *Auth) VerifyEmail(c *gin.Context, req *types.AuthVerifyEmailReq) (*types.AuthMessageResp, error) {
+	token, err := h.consumeUserToken(req.Token, model.TokenPurposeVerifyEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	// 邮箱在发出验证邮件后被修改过，则该token不再有效
+	result := h.DB.Model(&model.GodirUser{}).
+		Where("id = ? AND email = ?", token.UserID, token.Email).
+		Update("email_verified", true)
+	if result.Error != nil {
+		return nil, fmt.Errorf("验证邮箱失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("验证链接已失效")
+	}
+
+	return &types.AuthMessageResp{Message: "邮箱验证成功"}, nil
+}
+
+// ResendVerification 重新发送邮箱验证邮件
+func (h *Auth) ResendVerification(c *gin.Context, req *types.AuthResendVerificationReq) (*types.AuthMessageResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.GodirUser
+	if err := h.DB.First(&user, claims.UserID).Error; err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.Email == nil {
+		return nil, fmt.Errorf("尚未设置邮箱")
+	}
+	if user.EmailVerified {
+		return nil, fmt.Errorf("邮箱已验证")
+	}
+
+	if err := h.sendVerificationEmail(&user); err != nil {
+		h.Log.Errorf("发送验证邮件失败: %v", err)
+		return nil, fmt.Errorf("发送验证邮件失败，请稍后重试")
+	}
+
+	return &types.AuthMessageResp{Message: "验证邮件已发送"}, nil
+}
+
+// ForgotPassword 发送重置密码邮件；无论邮箱是否存在都返回相同结果，避免泄露注册信息
+func (h *Auth) ForgotPassword(c *gin.Context, req *types.AuthForgotPasswordReq) (*types.AuthMessageResp, error) {
+	resp := &types.AuthMessageResp{Message: "如果该邮箱已注册，重置密码邮件将很快送达"}
+
+	email := normalizeEmail(req.Email)
+	var user model.GodirUser
+	if err := h.DB.Where("email = ? AND email_verified = ?", email, true).First(&user).Error; err != nil {
+		return resp, nil
+	}
+
+	if !h.takeMailCooldown(email) {
+		return resp, nil
+	}
+
+	// 作废之前未使用的重置token，只保留最新一封邮件中的链接
+	h.DB.Model(&model.GodirUserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, model.TokenPurposeResetPassword).
+		Update("used_at", time.Now())
+
+	token, err := h.createUserToken(&user, email, model.TokenPurposeResetPassword, resetPasswordTokenTTL)
+	if err != nil {
+		h.Log.Errorf("创建重置密码token失败: %v", err)
+		return resp, nil
+	}
+
+	link := buildLink("/reset-password", token)
+	err = svc.Mailer().Send(h.Ctx, &mailer.Message{
+		To:      email,
+		Subject: "godir 重置密码",
+		Body: fmt.Sprintf("%s，您好：\n\n我们收到了重置密码的请求，请在%d分钟内打开以下链接设置新密码：\n%s\n\n如果不是您本人操作，请忽略此邮件。",
+			user.Username, int(resetPasswordTokenTTL.Minutes()), link),
+	})
+	if err != nil {
+		h.Log.Errorf("发送重置密码邮件失败: %v", err)
+	}
+
+	return resp, nil
+}
+
+// ResetPassword 使用邮件中的token重置密码，并使该用户所有已登录会话失效
+func (h *Auth) ResetPassword(c *gin.Context, req *types.AuthResetPasswordReq) (*types.AuthMessageResp, error) {
+	token, err := h.consumeUserToken(req.Token, model.TokenPurposeResetPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := HashPassword(req.NewPassword)
+	if err != nil {
+		return nil, fmt.Errorf("密码加密失败: %w", err)
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.GodirUser{}).Where("id = ?", token.UserID).Update("password", hashedPassword).Error; err != nil {
+			return err
+		}
+		return revokeAllSessions(tx, token.UserID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("重置密码失败: %w", err)
+	}
+
+	if _, err := jwt.RevokeAll(h.Ctx, token.UserID); err != nil {
+		h.Log.Errorf("吊销用户token失败: %v", err)
+	}
+
+	return &types.AuthMessageResp{Message: "密码已重置，请重新登录"}, nil
+}
+
+// sendVerificationEmail 生成邮箱验证token并发送邮件
+func (h *Auth) sendVerificationEmail(user *model.GodirUser) error {
+	email := user.GetEmail()
+	if !h.takeMailCooldown(email) {
+		return fmt.Errorf("发送过于频繁")
+	}
+
+	token, err := h.createUserToken(user, email, model.TokenPurposeVerifyEmail, verifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	link := buildLink("/verify-email", token)
+	return svc.Mailer().Send(h.Ctx, &mailer.Message{
+		To:      email,
+		Subject: "godir 邮箱验证",
+		Body: fmt.Sprintf("%s，您好：\n\n请在%d小时内打开以下链接完成邮箱验证：\n%s\n\n如果不是您本人操作，请忽略此邮件。",
+			user.Username, int(verifyEmailTokenTTL.Hours()), link),
+	})
+}
+
+// ChangeEmail 设置或修改邮箱：向新邮箱发送确认邮件，确认后才保存；设置过密码的账号需验证当前密码
+func (h *Auth) ChangeEmail(c *gin.Context, req *types.AuthChangeEmailReq) (*types.AuthMessageResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.GodirUser
+	if err := h.DB.First(&user, claims.UserID).Error; err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.Password != "" && !VerifyPassword(req.Password, user.Password) {
+		return nil, fmt.Errorf("密码错误")
+	}
+
+	email := normalizeEmail(req.Email)
+	if email == user.GetEmail() && user.EmailVerified {
+		return nil, fmt.Errorf("新邮箱与当前邮箱相同")
+	}
+	var count int64
+	if err := h.DB.Model(&model.GodirUser{}).Where("email = ? AND id <> ?", email, user.ID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("修改邮箱失败: %w", err)
+	}
+	if count > 0 {
+		return nil, fmt.Errorf("邮箱已被使用")
+	}
+
+	if !h.takeMailCooldown(email) {
+		return nil, fmt.Errorf("发送过于频繁")
+	}
+
+	// 只保留最新一次申请的确认链接
+	h.DB.Model(&model.GodirUserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, model.TokenPurposeChangeEmail).
+		Update("used_at", time.Now())
+
+	token, err := h.createUserToken(&user, email, model.TokenPurposeChangeEmail, changeEmailTokenTTL)
+	if err != nil {
+		h.Log.Errorf("创建修改邮箱token失败: %v", err)
+		return nil, fmt.Errorf("发送确认邮件失败，请稍后重试")
+	}
+
+	link := buildLink("/confirm-email", token)
+	err = svc.Mailer().Send(h.Ctx, &mailer.Message{
+		To:      email,
+		Subject: "godir 确认新邮箱",
+		Body: fmt.Sprintf("%s，您好：\n\n请在%d小时内打开以下链接，将此邮箱设为您的账号邮箱：\n%s\n\n如果不是您本人操作，请忽略此邮件。",
+			user.Username, int(changeEmailTokenTTL.Hours()), link),
+	})
+	if err != nil {
+		h.Log.Errorf("发送修改邮箱确认邮件失败: %v", err)
+		return nil, fmt.Errorf("发送确认邮件失败，请稍后重试")
+	}
+
+	return &types.AuthMessageResp{Message: "确认邮件已发送到新邮箱，确认后生效"}, nil
+}
+
+// ConfirmEmailChange 使用发往新邮箱的token确认修改，新邮箱同时视为已验证
+func (h *Auth) ConfirmEmailChange(c *gin.Context, req *types.AuthConfirmEmailChangeReq) (*types.AuthMessageResp, error) {
+	token, err := h.consumeUserToken(req.Token, model.TokenPurposeChangeEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.GodirUser
+	if err := h.DB.First(&user, token.UserID).Error; err != nil {
+		return nil, fmt.Errorf("链接无效或已过期")
+	}
+	oldEmail, oldVerified := user.GetEmail(), user.EmailVerified
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		// 申请之后邮箱可能已被其他账号占用
+		var count int64
+		if err := tx.Model(&model.GodirUser{}).Where("email = ? AND id <> ?", token.Email, user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("邮箱已被使用")
+		}
+		// 与其他账号并发确认同一邮箱时由唯一索引拦截
+		if err := tx.Model(&user).Updates(map[string]interface{}{"email": token.Email, "email_verified": true}).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return fmt.Errorf("邮箱已被使用")
+			}
+			return err
+		}
+		// 发往旧邮箱的验证、重置密码链接一并作废
+		return tx.Model(&model.GodirUserToken{}).
+			Where("user_id = ? AND purpose IN ? AND used_at IS NULL", user.ID,
+				[]string{model.TokenPurposeVerifyEmail, model.TokenPurposeResetPassword}).
+			Update("used_at", time.Now()).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("修改邮箱失败: %w", err)
+	}
+
+	// 通知旧邮箱，便于发现账号被他人修改
+	if oldEmail != "" && oldVerified && oldEmail != token.Email {
+		err := svc.Mailer().Send(h.Ctx, &mailer.Message{
+			To:      oldEmail,
+			Subject: "godir 账号邮箱已修改",
+			Body: fmt.Sprintf("%s，您好：\n\n您的账号邮箱已修改为 %s。\n\n如果不是您本人操作，请立即修改密码并联系管理员。",
+				user.Username, token.Email),
+		})
+		if err != nil {
+			h.Log.Warnf("发送邮箱修改通知失败: %v", err)
+		}
+	}
+
+	return &types.AuthMessageResp{Message: "邮箱已修改"}, nil
+}
+
+// createUserToken 创建一次性token，返回明文；email 为token对应的邮箱（修改邮箱时为新邮箱）
+func (h *Auth) createUserToken(user *model.GodirUser, email, purpose string, ttl time.Duration) (string, error) {
+	token, hash := newOpaqueToken()
+	row := model.GodirUserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     email,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := h.DB.Create(&row).Error; err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeUserToken 校验并消费一次性token，条件更新保证只能使用一次
+func (h *Auth) consumeUserToken(token, purpose string) (*model.GodirUserToken, error) {
+	var row model.GodirUserToken
+	if err := h.DB.Where("token_hash = ? AND purpose = ?", hashToken(token), purpose).First(&row).Error; err != nil {
+		return nil, fmt.Errorf("链接无效或已过期")
+	}
+	if row.UsedAt != nil || time.Now().After(row.ExpiresAt) {
+		return nil, fmt.Errorf("链接无效或已过期")
+	}
+
+	result := h.DB.Model(&model.GodirUserToken{}).
+		Where("id = ? AND used_at IS NULL", row.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, fmt.Errorf("校验链接失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("链接无效或已过期")
+	}
+	return &row, nil
+}
+
+// takeMailCooldown 限制同一邮箱的发信频率，返回false表示仍在冷却中
+func (h *Auth) takeMailCooldown(email string) bool {
+	ok, err := svc.Redis().SetNX(context.Background(), mailCooldownKeyPrefix+email, 1, mailCooldown).Result()
+	if err != nil {
+		h.Log.Warnf("检查发信频率失败: %v", err)
+		return true
+	}
+	return ok
+}
+
+// newOpaqueToken 生成随机token，返回明文和用于存储的哈希
+func newOpaqueToken() (token string, hash string) {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token)
+}
+
+func buildLink(path, token string) string {
+	base := strings.TrimRight(svc.Cfg().Mail.LinkBaseURL, "/")
+	return base + path + "?token=" + url.QueryEscape(token)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
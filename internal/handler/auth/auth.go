@@ -1,15 +1,22 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"time"
 
+	"godir/internal/common/exterr"
 	"godir/internal/common/ginx"
 	"godir/internal/common/jwt"
+	"godir/internal/common/loginguard"
 	"godir/internal/model"
 	"godir/internal/types"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Auth struct {
@@ -23,32 +30,206 @@ func (h *Auth) New() ginx.BaseHandlerInterface {
 
 // Login 用户登录
 func (h *Auth) Login(c *gin.Context, req *types.AuthLoginReq) (*types.AuthLoginResp, error) {
+	ip := c.ClientIP()
+
+	// 检查账号/IP是否被锁定或处于退避期
+	block, err := loginguard.Check(h.Ctx, req.Username, ip)
+	if err != nil {
+		h.Log.Errorf("检查登录限制失败: %v", err)
+		return nil, fmt.Errorf("登录失败，请稍后重试")
+	}
+	if block != nil {
+		return nil, blockedError(c, block)
+	}
 
 	// 查询用户
 	var user model.GodirUser
 
 	if err := h.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		return nil, fmt.Errorf("用户名或密码错误")
+		return nil, h.loginFailed(c, req.Username, 0)
 	}
 
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, fmt.Errorf("用户名或密码错误")
+		return nil, h.loginFailed(c, req.Username, user.ID)
+	}
+
+	if err := loginguard.Succeed(h.Ctx, req.Username); err != nil {
+		h.Log.Warnf("清除登录失败记录失败: %v", err)
 	}
 
-	// 生成JWT token
-	token, err := jwt.GenerateToken(user.ID, user.Username)
+	return h.completeLogin(c, &user)
+}
+
+// completeLogin 第一因素验证通过后完成登录：
+// 已启用两步验证时只下发短期挑战token，否则签发访问token和新家族的刷新token
+func (h *Auth) completeLogin(c *gin.Context, user *model.GodirUser) (*types.AuthLoginResp, error) {
+	if user.TOTPEnabled {
+		challenge, err := h.newChallenge(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AuthLoginResp{
+			UserID:            user.ID,
+			Username:          user.Username,
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		}, nil
+	}
+
+	tokens, err := h.issueTokens(c, user, jwt.NewFamilyID())
 	if err != nil {
-		return nil, fmt.Errorf("生成token失败: %w", err)
+		return nil, err
 	}
 
 	return &types.AuthLoginResp{
-		Token:    token,
-		UserID:   user.ID,
-		Username: user.Username,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		UserID:       user.ID,
+		Username:     user.Username,
 	}, nil
 }
 
+// Refresh 使用刷新token换取新的访问token，并轮换刷新token
+func (h *Auth) Refresh(c *gin.Context, req *types.AuthRefreshReq) (*types.AuthRefreshResp, error) {
+	var rt model.GodirRefreshToken
+	if err := h.DB.Where("token_hash = ?", jwt.HashRefreshToken(req.RefreshToken)).First(&rt).Error; err != nil {
+		return nil, exterr.New(exterr.CodeInvalidRefreshToken, "刷新token无效")
+	}
+
+	if rt.RevokedAt != nil || time.Now().After(rt.ExpiresAt) {
+		return nil, exterr.New(exterr.CodeInvalidRefreshToken, "刷新token已失效，请重新登录")
+	}
+
+	// 标记为已使用；条件更新保证并发下只有一个请求能完成轮换
+	now := time.Now()
+	result := h.DB.Model(&model.GodirRefreshToken{}).
+		Where("id = ? AND used_at IS NULL", rt.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return nil, fmt.Errorf("刷新token失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// 已使用过的刷新token再次出现，说明可能被盗用，结束整个会话
+		h.Log.Warnf("检测到刷新token重复使用, user_id=%d family_id=%s", rt.UserID, rt.FamilyID)
+		if err := revokeSession(h.Ctx, h.DB, rt.UserID, rt.FamilyID); err != nil {
+			h.Log.Errorf("吊销刷新token家族失败: %v", err)
+		}
+		return nil, exterr.New(exterr.CodeRefreshTokenReused, "刷新token已失效，请重新登录")
+	}
+
+	var user model.GodirUser
+	if err := h.DB.First(&user, rt.UserID).Error; err != nil {
+		return nil, exterr.New(exterr.CodeInvalidRefreshToken, "用户不存在")
+	}
+
+	return h.issueTokens(c, &user, rt.FamilyID)
+}
+
+// loginFailed 记录登录失败，触发锁定时写入审计记录并返回锁定错误
+func (h *Auth) loginFailed(c *gin.Context, username string, userID uint) error {
+	ip := c.ClientIP()
+
+	res, err := loginguard.Fail(h.Ctx, username, ip)
+	if err != nil {
+		h.Log.Errorf("记录登录失败次数失败: %v", err)
+		return fmt.Errorf("用户名或密码错误")
+	}
+
+	if res.UserLocked || res.IPLocked {
+		lock := loginguard.LockDuration()
+		audit := model.GodirAuditLog{
+			UserID:   userID,
+			Username: username,
+			IP:       ip,
+		}
+		if res.UserLocked {
+			audit.Action = model.AuditActionAccountLockout
+			audit.Detail = fmt.Sprintf("用户名连续登录失败%d次，锁定%s", res.UserFailures, lock)
+		} else {
+			audit.Action = model.AuditActionIPLockout
+			audit.Detail = fmt.Sprintf("IP连续登录失败%d次，锁定%s", res.IPFailures, lock)
+		}
+		if err := h.DB.Create(&audit).Error; err != nil {
+			h.Log.Errorf("写入审计记录失败: %v", err)
+		}
+		h.Log.Warnf("登录锁定: action=%s username=%s ip=%s", audit.Action, username, ip)
+
+		return blockedError(c, &loginguard.Block{Locked: true, RetryAfter: lock})
+	}
+
+	return fmt.Errorf("用户名或密码错误")
+}
+
+// blockedError 构造登录受限错误，并通过 Retry-After 头告知需要等待的秒数
+func blockedError(c *gin.Context, block *loginguard.Block) error {
+	seconds := int64(math.Ceil(block.RetryAfter.Seconds()))
+	c.Header("Retry-After", fmt.Sprintf("%d", seconds))
+
+	if block.Locked {
+		minutes := int64(math.Ceil(block.RetryAfter.Minutes()))
+		return exterr.Newf(exterr.CodeLoginLocked, "登录失败次数过多，请在%d分钟后重试", minutes)
+	}
+	return exterr.Newf(exterr.CodeLoginThrottled, "登录过于频繁，请在%d秒后重试", seconds)
+}
+
+// issueTokens 签发访问token，并在指定家族下生成新的刷新token，同时记录/更新对应会话
+func (h *Auth) issueTokens(c *gin.Context, user *model.GodirUser, familyID string) (*types.AuthRefreshResp, error) {
+	token, tokenID, err := jwt.GenerateToken(user.ID, user.Username, user.Role, familyID)
+	if err != nil {
+		return nil, fmt.Errorf("生成token失败: %w", err)
+	}
+
+	// 新家族即新登录，插入会话；刷新时更新最近签发的token和活跃信息
+	session := model.GodirSession{
+		SessionID:  familyID,
+		UserID:     user.ID,
+		TokenID:    tokenID,
+		UserAgent:  truncate(c.Request.UserAgent(), 512),
+		IP:         c.ClientIP(),
+		LastSeenAt: time.Now(),
+	}
+	if err := h.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "session_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token_id", "user_agent", "ip", "last_seen_at", "updated_at"}),
+	}).Create(&session).Error; err != nil {
+		return nil, fmt.Errorf("保存会话失败: %w", err)
+	}
+
+	refreshToken, hash := jwt.NewRefreshToken()
+	rt := model.GodirRefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(jwt.RefreshTokenExp()),
+	}
+	if err := h.DB.Create(&rt).Error; err != nil {
+		return nil, fmt.Errorf("保存刷新token失败: %w", err)
+	}
+
+	return &types.AuthRefreshResp{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(jwt.TokenExp().Seconds()),
+	}, nil
+}
+
+// truncate 截断过长的字符串（按字节）
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// revokeFamily 吊销某个家族下的所有刷新token
+func (h *Auth) revokeFamily(familyID string) error {
+	return h.DB.Model(&model.GodirRefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
 // Register 用户注册
 func (h *Auth) Register(c *gin.Context, req *types.AuthRegisterReq) (*types.AuthRegisterResp, error) {
 	// 检查用户名是否已存在
@@ -57,6 +238,13 @@ func (h *Auth) Register(c *gin.Context, req *types.AuthRegisterReq) (*types.Auth
 		return nil, fmt.Errorf("用户名已存在")
 	}
 
+	email := normalizeEmail(req.Email)
+	if email != "" {
+		if err := h.DB.Where("email = ?", email).First(&existingUser).Error; err == nil {
+			return nil, fmt.Errorf("邮箱已被使用")
+		}
+	}
+
 	// 加密密码
 	hashedPassword, err := HashPassword(req.Password)
 	if err != nil {
@@ -67,28 +255,106 @@ func (h *Auth) Register(c *gin.Context, req *types.AuthRegisterReq) (*types.Auth
 	user := model.GodirUser{
 		Username: req.Username,
 		Password: hashedPassword,
+		Role:     model.RoleEditor,
+		Email:    model.NullEmail(email),
 	}
 
+	// 并发注册同一邮箱时由唯一索引拦截
 	if err := h.DB.Create(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fmt.Errorf("邮箱已被使用")
+		}
 		return nil, fmt.Errorf("创建用户失败: %w", err)
 	}
 
+	message := "注册成功"
+	if email != "" {
+		// 验证邮件发送失败不影响注册，用户可稍后重新发送
+		if err := h.sendVerificationEmail(&user); err != nil {
+			h.Log.Errorf("发送验证邮件失败: %v", err)
+		} else {
+			message = "注册成功，请前往邮箱完成验证"
+		}
+	}
+
 	return &types.AuthRegisterResp{
 		UserID:   user.ID,
 		Username: user.Username,
-		Message:  "注册成功",
+		Message:  message,
 	}, nil
 }
 
-// Logout 用户退出
+// Logout 用户退出，服务端吊销当前token
 func (h *Auth) Logout(c *gin.Context, req *types.AuthLogoutReq) (*types.AuthLogoutResp, error) {
-	// JWT是无状态的，退出主要是客户端删除token
-	// 如果需要服务端控制，可以实现token黑名单机制
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := jwt.Revoke(h.Ctx, claims); err != nil {
+		h.Log.Errorf("吊销token失败: %v", err)
+		return nil, fmt.Errorf("退出失败")
+	}
+
+	// 结束当前会话，会话ID即刷新token家族ID
+	if claims.Sid != "" {
+		if err := revokeSession(h.Ctx, h.DB, claims.UserID, claims.Sid); err != nil {
+			h.Log.Errorf("结束会话失败: %v", err)
+		}
+	}
+
+	// 同时吊销客户端持有的刷新token（兼容不带会话ID的旧token）
+	if req.RefreshToken != "" {
+		var rt model.GodirRefreshToken
+		err := h.DB.Where("token_hash = ? AND user_id = ?", jwt.HashRefreshToken(req.RefreshToken), claims.UserID).First(&rt).Error
+		if err == nil {
+			if err := h.revokeFamily(rt.FamilyID); err != nil {
+				h.Log.Errorf("吊销刷新token失败: %v", err)
+			}
+		}
+	}
+
 	return &types.AuthLogoutResp{
 		Message: "退出成功",
 	}, nil
 }
 
+// LogoutAll 退出所有设备，使该用户已签发的全部token失效
+func (h *Auth) LogoutAll(c *gin.Context, req *types.AuthLogoutAllReq) (*types.AuthLogoutResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := jwt.RevokeAll(h.Ctx, claims.UserID); err != nil {
+		h.Log.Errorf("吊销用户全部token失败: %v", err)
+		return nil, fmt.Errorf("退出失败")
+	}
+
+	if err := revokeAllSessions(h.DB, claims.UserID); err != nil {
+		h.Log.Errorf("吊销用户刷新token失败: %v", err)
+		return nil, fmt.Errorf("退出失败")
+	}
+
+	return &types.AuthLogoutResp{
+		Message: "已退出所有设备",
+	}, nil
+}
+
+// currentClaims 从上下文获取认证中间件写入的token信息
+func currentClaims(c *gin.Context) (*jwt.Claims, error) {
+	userInfo, exists := c.Get("userInfo")
+	if !exists {
+		return nil, fmt.Errorf("无法获取用户信息")
+	}
+
+	claims, ok := userInfo.(jwt.Claims)
+	if !ok {
+		return nil, fmt.Errorf("用户信息格式错误")
+	}
+	return &claims, nil
+}
+
 // HashPassword 加密密码
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
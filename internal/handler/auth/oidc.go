@@ -0,0 +1,372 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"godir/internal/common/oidc"
+	"godir/internal/common/svc"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	oidcStateKeyPrefix = "auth:oidc:state:"
+	oidcStateTTL       = 10 * time.Minute
+)
+
+// oidcState 发起授权时保存在Redis中的上下文，回调时一次性取出
+type oidcState struct {
+	Provider   string `json:"provider"`
+	Verifier   string `json:"verifier"`
+	Nonce      string `json:"nonce"`
+	LinkUserID uint   `json:"link_user_id,omitempty"` // 非0表示为已登录用户关联身份
+	LinkSid    string `json:"link_sid,omitempty"`     // 发起关联的登录会话，完成关联时须为同一会话
+}
+
+// OIDCProviders 返回已配置的身份提供方，供登录页展示按钮
+func (h *Auth) OIDCProviders(c *gin.Context, req *types.AuthOIDCProvidersReq) (*types.AuthOIDCProvidersResp, error) {
+	return &types.AuthOIDCProvidersResp{Providers: oidc.Names()}, nil
+}
+
+// OIDCLogin 发起OIDC登录，返回身份提供方的授权地址
+func (h *Auth) OIDCLogin(c *gin.Context, req *types.AuthOIDCStartReq) (*types.AuthOIDCStartResp, error) {
+	return h.startOIDC(req.Provider, 0, "")
+}
+
+// OIDCLink 已登录用户发起外部身份关联，回调须提交到 OIDCLinkCallback
+func (h *Auth) OIDCLink(c *gin.Context, req *types.AuthOIDCStartReq) (*types.AuthOIDCStartResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+	return h.startOIDC(req.Provider, claims.UserID, claims.Sid)
+}
+
+// OIDCCallback 登录流程的回调：用授权码换取ID Token并签发godir token。
+// 该接口无需登录，关联流程的state在这里一律拒绝，否则他人发起的关联链接可把受害者的外部身份关联到发起者的账号
+func (h *Auth) OIDCCallback(c *gin.Context, req *types.AuthOIDCCallbackReq) (*types.AuthOIDCCallbackResp, error) {
+	state, err := h.takeOIDCState(req.State)
+	if err != nil {
+		return nil, err
+	}
+	if state.LinkUserID != 0 {
+		return nil, fmt.Errorf("关联外部身份需在登录状态下完成")
+	}
+
+	provider, idClaims, err := h.exchangeOIDC(state, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, found, err := h.findIdentity(state.Provider, idClaims.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.GodirUser
+	if found {
+		if err := h.DB.First(&user, identity.UserID).Error; err != nil {
+			return nil, fmt.Errorf("用户不存在")
+		}
+	} else {
+		u, err := h.provisionOIDCUser(provider.Config(), idClaims)
+		if err != nil {
+			return nil, err
+		}
+		user = *u
+	}
+
+	resp, err := h.completeLogin(c, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &types.AuthOIDCCallbackResp{AuthLoginResp: *resp}, nil
+}
+
+// OIDCLinkCallback 关联流程的回调，需登录：只有发起关联的用户在同一会话中才能完成关联并写入身份记录
+func (h *Auth) OIDCLinkCallback(c *gin.Context, req *types.AuthOIDCCallbackReq) (*types.AuthOIDCCallbackResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := h.takeOIDCState(req.State)
+	if err != nil {
+		return nil, err
+	}
+	if state.LinkUserID == 0 || state.LinkUserID != claims.UserID || state.LinkSid != claims.Sid {
+		return nil, fmt.Errorf("关联请求无效，请重新发起")
+	}
+
+	_, idClaims, err := h.exchangeOIDC(state, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, found, err := h.findIdentity(state.Provider, idClaims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		if identity.UserID != claims.UserID {
+			return nil, fmt.Errorf("该外部账号已关联其他用户")
+		}
+		return &types.AuthOIDCCallbackResp{Linked: true}, nil
+	}
+
+	identity = &model.GodirUserIdentity{
+		UserID:   claims.UserID,
+		Provider: state.Provider,
+		Subject:  idClaims.Subject,
+		Email:    idClaims.Email,
+	}
+	if err := h.DB.Create(identity).Error; err != nil {
+		return nil, fmt.Errorf("关联外部身份失败: %w", err)
+	}
+	return &types.AuthOIDCCallbackResp{Linked: true}, nil
+}
+
+// takeOIDCState 一次性取出发起授权时保存的state
+func (h *Auth) takeOIDCState(stateID string) (*oidcState, error) {
+	raw, err := svc.Redis().GetDel(h.Ctx, oidcStateKeyPrefix+stateID).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("登录已过期，请重新发起")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取登录状态失败: %w", err)
+	}
+
+	var state oidcState
+	if err := json.Unmarshal([]byte(raw), &state); err != nil {
+		return nil, fmt.Errorf("登录状态格式错误")
+	}
+	return &state, nil
+}
+
+// exchangeOIDC 用授权码换取并校验ID Token
+func (h *Auth) exchangeOIDC(state *oidcState, code string) (*oidc.Provider, *oidc.IDClaims, error) {
+	provider, err := oidc.Get(h.Ctx, state.Provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	idClaims, err := provider.Exchange(h.Ctx, code, state.Verifier, state.Nonce)
+	if err != nil {
+		h.Log.Warnf("OIDC回调校验失败, provider=%s: %v", state.Provider, err)
+		return nil, nil, fmt.Errorf("身份提供方登录失败")
+	}
+	return provider, idClaims, nil
+}
+
+// findIdentity 按提供方和subject查找已关联的外部身份
+func (h *Auth) findIdentity(provider, subject string) (*model.GodirUserIdentity, bool, error) {
+	var identity model.GodirUserIdentity
+	err := h.DB.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("查询外部身份失败: %w", err)
+	}
+	return &identity, true, nil
+}
+
+// ListIdentities 当前用户已关联的外部身份
+func (h *Auth) ListIdentities(c *gin.Context, req *types.AuthIdentityListReq) (*types.AuthIdentityListResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var identities []model.GodirUserIdentity
+	if err := h.DB.Where("user_id = ?", claims.UserID).Order("id").Find(&identities).Error; err != nil {
+		return nil, fmt.Errorf("查询外部身份失败: %w", err)
+	}
+
+	list := make([]types.AuthIdentityItem, 0, len(identities))
+	for _, identity := range identities {
+		list = append(list, types.AuthIdentityItem{
+			ID:        identity.ID,
+			Provider:  identity.Provider,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return &types.AuthIdentityListResp{List: list}, nil
+}
+
+// UnlinkIdentity 解除外部身份关联；没有本地密码时不允许解除最后一个身份
+func (h *Auth) UnlinkIdentity(c *gin.Context, req *types.AuthIdentityUnlinkReq) (*types.AuthMessageResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var user model.GodirUser
+		if err := tx.First(&user, claims.UserID).Error; err != nil {
+			return fmt.Errorf("获取用户信息失败: %w", err)
+		}
+
+		var identity model.GodirUserIdentity
+		if err := tx.Where("id = ? AND user_id = ?", req.ID, claims.UserID).First(&identity).Error; err != nil {
+			return fmt.Errorf("外部身份不存在")
+		}
+
+		if user.Password == "" {
+			var count int64
+			if err := tx.Model(&model.GodirUserIdentity{}).Where("user_id = ?", claims.UserID).Count(&count).Error; err != nil {
+				return fmt.Errorf("查询外部身份失败: %w", err)
+			}
+			if count <= 1 {
+				return fmt.Errorf("这是唯一的登录方式，请先设置密码或关联其他身份")
+			}
+		}
+
+		return tx.Unscoped().Delete(&identity).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.AuthMessageResp{Message: "已解除关联"}, nil
+}
+
+// startOIDC 生成state、nonce和PKCE verifier并保存，返回授权地址
+func (h *Auth) startOIDC(name string, linkUserID uint, linkSid string) (*types.AuthOIDCStartResp, error) {
+	provider, err := oidc.Get(h.Ctx, name)
+	if err != nil {
+		h.Log.Warnf("获取身份提供方失败: %v", err)
+		return nil, fmt.Errorf("身份提供方不可用")
+	}
+
+	stateID := oidc.NewRandom()
+	state := oidcState{
+		Provider:   name,
+		Verifier:   oidc.NewRandom(),
+		Nonce:      oidc.NewRandom(),
+		LinkUserID: linkUserID,
+		LinkSid:    linkSid,
+	}
+	data, _ := json.Marshal(state)
+	if err := svc.Redis().Set(h.Ctx, oidcStateKeyPrefix+stateID, data, oidcStateTTL).Err(); err != nil {
+		return nil, fmt.Errorf("保存登录状态失败: %w", err)
+	}
+
+	return &types.AuthOIDCStartResp{
+		AuthURL: provider.AuthURL(stateID, state.Nonce, state.Verifier),
+	}, nil
+}
+
+// provisionOIDCUser 外部身份首次登录：按配置关联同邮箱的已验证用户，否则创建新用户
+func (h *Auth) provisionOIDCUser(cfg svc.OIDCProviderConfig, idClaims *oidc.IDClaims) (*model.GodirUser, error) {
+	email := ""
+	if idClaims.EmailVerified {
+		email = normalizeEmail(idClaims.Email)
+	}
+
+	var user model.GodirUser
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		linked := false
+		if cfg.AutoLinkByEmail && email != "" {
+			err := tx.Where("email = ? AND email_verified = ?", email, true).First(&user).Error
+			if err == nil {
+				linked = true
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("查询用户失败: %w", err)
+			}
+		}
+
+		if !linked {
+			username, err := uniqueUsername(tx, oidcUsername(cfg.Name, idClaims))
+			if err != nil {
+				return err
+			}
+			user = model.GodirUser{
+				Username:      username,
+				Role:          model.RoleEditor,
+				Nickname:      idClaims.Name,
+				Email:         model.NullEmail(email),
+				EmailVerified: email != "",
+			}
+			// 邮箱已被本地账号占用时不写入，避免出现两个账号共用同一邮箱
+			if email != "" {
+				var count int64
+				if err := tx.Model(&model.GodirUser{}).Where("email = ?", email).Count(&count).Error; err != nil {
+					return fmt.Errorf("查询用户失败: %w", err)
+				}
+				if count > 0 {
+					user.Email, user.EmailVerified = nil, false
+				}
+			}
+			err = tx.Create(&user).Error
+			if errors.Is(err, gorm.ErrDuplicatedKey) && user.Email != nil {
+				// 查询之后邮箱被并发占用
+				user.Email, user.EmailVerified = nil, false
+				err = tx.Create(&user).Error
+			}
+			if err != nil {
+				return fmt.Errorf("创建用户失败: %w", err)
+			}
+		}
+
+		identity := model.GodirUserIdentity{
+			UserID:   user.ID,
+			Provider: cfg.Name,
+			Subject:  idClaims.Subject,
+			Email:    idClaims.Email,
+		}
+		if err := tx.Create(&identity).Error; err != nil {
+			return fmt.Errorf("关联外部身份失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// oidcUsername 从ID Token中挑选一个用户名候选
+func oidcUsername(provider string, idClaims *oidc.IDClaims) string {
+	name := idClaims.PreferredUsername
+	if name == "" && idClaims.Email != "" {
+		name, _, _ = strings.Cut(idClaims.Email, "@")
+	}
+	if name == "" {
+		sub := idClaims.Subject
+		if len(sub) > 8 {
+			sub = sub[:8]
+		}
+		name = provider + "_" + sub
+	}
+	if len(name) > 100 {
+		name = name[:100]
+	}
+	return name
+}
+
+// uniqueUsername 用户名已存在时追加数字后缀
+func uniqueUsername(tx *gorm.DB, base string) (string, error) {
+	name := base
+	for i := 2; i < 100; i++ {
+		var count int64
+		if err := tx.Model(&model.GodirUser{}).Where("username = ?", name).Count(&count).Error; err != nil {
+			return "", fmt.Errorf("查询用户失败: %w", err)
+		}
+		if count == 0 {
+			return name, nil
+		}
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	return "", fmt.Errorf("无法生成可用的用户名")
+}
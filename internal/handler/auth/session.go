@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"godir/internal/common/jwt"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListSessions 列出当前用户仍有效的登录会话
+func (h *Auth) ListSessions(c *gin.Context, req *types.SessionListReq) (*types.SessionListResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// 超过刷新token有效期未活跃的会话已无法续期，不再展示
+	var sessions []model.GodirSession
+	if err := h.DB.Where("user_id = ? AND revoked_at IS NULL AND last_seen_at > ?", claims.UserID, time.Now().Add(-jwt.RefreshTokenExp())).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("查询会话失败: %w", err)
+	}
+
+	list := make([]types.SessionItem, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, types.SessionItem{
+			SessionID:  s.SessionID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt.Format("2006-01-02 15:04:05"),
+			LastSeenAt: s.LastSeenAt.Format("2006-01-02 15:04:05"),
+			Current:    s.SessionID == claims.Sid,
+		})
+	}
+
+	return &types.SessionListResp{List: list}, nil
+}
+
+// RevokeSession 结束指定会话，对应设备需重新登录
+func (h *Auth) RevokeSession(c *gin.Context, req *types.SessionRevokeReq) (*types.AuthMessageResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var session model.GodirSession
+	if err := h.DB.Where("session_id = ? AND user_id = ?", req.SessionID, claims.UserID).First(&session).Error; err != nil {
+		return nil, fmt.Errorf("会话不存在")
+	}
+	if session.RevokedAt != nil {
+		return &types.AuthMessageResp{Message: "会话已结束"}, nil
+	}
+
+	if err := revokeSession(h.Ctx, h.DB, claims.UserID, session.SessionID); err != nil {
+		h.Log.Errorf("结束会话失败: %v", err)
+		return nil, fmt.Errorf("结束会话失败")
+	}
+
+	return &types.AuthMessageResp{Message: "会话已结束"}, nil
+}
+
+// revokeSession 吊销会话及其刷新token家族，并让该会话已签发的访问token立即失效
+func revokeSession(ctx context.Context, db *gorm.DB, userID uint, sessionID string) error {
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.GodirSession{}).
+			Where("session_id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+			Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.GodirRefreshToken{}).
+			Where("family_id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return jwt.RevokeSession(ctx, sessionID)
+}
+
+// revokeAllSessions 在库中结束用户的全部会话和刷新token，访问token需另行通过 jwt.RevokeAll 失效
+func revokeAllSessions(tx *gorm.DB, userID uint) error {
+	now := time.Now()
+	if err := tx.Model(&model.GodirSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&model.GodirRefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
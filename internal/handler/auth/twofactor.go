@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"godir/internal/common/exterr"
+	"godir/internal/common/jwt"
+	"godir/internal/common/loginguard"
+	"godir/internal/common/svc"
+	"godir/internal/common/totp"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	totpIssuer = "godir"
+
+	challengeKeyPrefix  = "auth:2fa:challenge:"
+	challengeTTL        = 5 * time.Minute
+	challengeMaxAttempt = 5
+
+	totpUsedKeyPrefix = "auth:2fa:used:"
+	recoveryCodeCount = 10
+)
+
+// Login2FA 两步验证登录第二步：校验挑战token和验证码后签发正式token
+func (h *Auth) Login2FA(c *gin.Context, req *types.AuthLogin2FAReq) (*types.AuthLoginResp, error) {
+	rdb := svc.Redis()
+	key := challengeKeyPrefix + hashToken(req.ChallengeToken)
+
+	userID, err := rdb.HGet(h.Ctx, key, "user_id").Uint64()
+	if errors.Is(err, redis.Nil) {
+		return nil, exterr.New(exterr.CodeChallengeExpired, "验证已过期，请重新登录")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取两步验证会话失败: %w", err)
+	}
+
+	// 限制单个挑战的尝试次数，超过后需重新输入密码
+	attempts, err := rdb.HIncrBy(h.Ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("读取两步验证会话失败: %w", err)
+	}
+	if attempts > challengeMaxAttempt {
+		rdb.Del(h.Ctx, key)
+		return nil, exterr.New(exterr.CodeChallengeExpired, "验证码错误次数过多，请重新登录")
+	}
+
+	var user model.GodirUser
+	if err := h.DB.First(&user, uint(userID)).Error; err != nil {
+		return nil, exterr.New(exterr.CodeChallengeExpired, "用户不存在")
+	}
+
+	ok, err := h.verifySecondFactor(&user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// 验证码错误同样计入登录失败次数
+		if err := h.loginFailed(c, user.Username, user.ID); exterr.Code(err) == exterr.CodeLoginLocked {
+			rdb.Del(h.Ctx, key)
+			return nil, err
+		}
+		return nil, exterr.New(exterr.CodeInvalidOTP, "验证码错误")
+	}
+
+	rdb.Del(h.Ctx, key)
+	if err := loginguard.Succeed(h.Ctx, user.Username); err != nil {
+		h.Log.Warnf("清除登录失败记录失败: %v", err)
+	}
+
+	tokens, err := h.issueTokens(c, &user, jwt.NewFamilyID())
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.AuthLoginResp{
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		UserID:       user.ID,
+		Username:     user.Username,
+	}, nil
+}
+
+// EnrollTwoFactor 生成待确认的TOTP密钥，返回供验证器App扫码的地址
+func (h *Auth) EnrollTwoFactor(c *gin.Context, req *types.TwoFactorEnrollReq) (*types.TwoFactorEnrollResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.GodirUser
+	if err := h.DB.First(&user, claims.UserID).Error; err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.TOTPEnabled {
+		return nil, fmt.Errorf("已启用两步验证，如需更换请先关闭")
+	}
+
+	secret := totp.GenerateSecret()
+	if err := h.DB.Model(&user).Update("totp_secret", secret).Error; err != nil {
+		return nil, fmt.Errorf("保存两步验证密钥失败: %w", err)
+	}
+
+	return &types.TwoFactorEnrollResp{
+		Secret:     secret,
+		OtpauthURI: totp.URI(totpIssuer, user.Username, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor 校验首个验证码后启用两步验证，并生成一次性恢复码
+func (h *Auth) ConfirmTwoFactor(c *gin.Context, req *types.TwoFactorConfirmReq) (*types.TwoFactorConfirmResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.GodirUser
+	if err := h.DB.First(&user, claims.UserID).Error; err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.TOTPEnabled {
+		return nil, fmt.Errorf("已启用两步验证")
+	}
+	if user.TOTPSecret == "" {
+		return nil, fmt.Errorf("请先获取两步验证密钥")
+	}
+
+	if !h.verifyTOTP(&user, req.Code) {
+		return nil, exterr.New(exterr.CodeInvalidOTP, "验证码错误")
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.GodirRecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		rows := make([]model.GodirRecoveryCode, 0, recoveryCodeCount)
+		for i := 0; i < recoveryCodeCount; i++ {
+			code := newRecoveryCode()
+			codes = append(codes, code)
+			rows = append(rows, model.GodirRecoveryCode{UserID: user.ID, CodeHash: hashToken(normalizeRecoveryCode(code))})
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("启用两步验证失败: %w", err)
+	}
+
+	return &types.TwoFactorConfirmResp{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor 关闭两步验证，需要提供验证码或恢复码
+func (h *Auth) DisableTwoFactor(c *gin.Context, req *types.TwoFactorDisableReq) (*types.TwoFactorDisableResp, error) {
+	claims, err := currentClaims(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.GodirUser
+	if err := h.DB.First(&user, claims.UserID).Error; err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if !user.TOTPEnabled {
+		return nil, fmt.Errorf("未启用两步验证")
+	}
+
+	ok, err := h.verifySecondFactor(&user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, exterr.New(exterr.CodeInvalidOTP, "验证码错误")
+	}
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": ""}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&model.GodirRecoveryCode{}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("关闭两步验证失败: %w", err)
+	}
+
+	return &types.TwoFactorDisableResp{Message: "已关闭两步验证"}, nil
+}
+
+// newChallenge 密码校验通过后创建两步验证挑战，返回挑战token明文
+func (h *Auth) newChallenge(userID uint) (string, error) {
+	token, hash := newOpaqueToken()
+
+	key := challengeKeyPrefix + hash
+	pipe := svc.Redis().TxPipeline()
+	pipe.HSet(h.Ctx, key, "user_id", userID, "attempts", 0)
+	pipe.Expire(h.Ctx, key, challengeTTL)
+	if _, err := pipe.Exec(h.Ctx); err != nil {
+		return "", fmt.Errorf("创建两步验证会话失败: %w", err)
+	}
+	return token, nil
+}
+
+// verifySecondFactor 校验TOTP验证码，不匹配时尝试作为恢复码消费
+func (h *Auth) verifySecondFactor(user *model.GodirUser, code string) (bool, error) {
+	if h.verifyTOTP(user, code) {
+		return true, nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return false, nil
+	}
+
+	// 条件更新保证恢复码只能被使用一次
+	result := h.DB.Model(&model.GodirRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashToken(normalized)).
+		Limit(1).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, fmt.Errorf("校验恢复码失败: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		h.Log.Infof("用户使用恢复码登录, user_id=%d", user.ID)
+		return true, nil
+	}
+	return false, nil
+}
+
+// verifyTOTP 校验TOTP验证码，同一时间步的验证码只能使用一次
+func (h *Auth) verifyTOTP(user *model.GodirUser, code string) bool {
+	if user.TOTPSecret == "" {
+		return false
+	}
+
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false
+	}
+
+	key := fmt.Sprintf("%s%d:%d", totpUsedKeyPrefix, user.ID, step)
+	fresh, err := svc.Redis().SetNX(h.Ctx, key, 1, 3*time.Minute).Result()
+	if err != nil {
+		h.Log.Errorf("记录验证码使用状态失败: %v", err)
+		return false
+	}
+	return fresh
+}
+
+// newRecoveryCode 生成形如 3f9a-c21b-7e04 的恢复码
+func newRecoveryCode() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	s := hex.EncodeToString(b)
+	return s[0:4] + "-" + s[4:8] + "-" + s[8:12]
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"godir/internal/common/apikey"
 	"godir/internal/common/ginx"
 	"godir/internal/handler/material"
+	"godir/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,15 +14,59 @@ func RegisterMaterialRouter(r *gin.Engine) {
 	protected := r.Group("/material")
 	protected.Use(ginx.AuthMiddleware())
 	{
-		protected.POST("/upload-token", ginx.WrapHandlerObj((*material.Material).GetUploadToken))
-		protected.POST("/save", ginx.WrapHandlerObj((*material.Material).Save))
-		protected.GET("/list", ginx.WrapHandlerObj((*material.Material).List))
-		protected.GET("/search", ginx.WrapHandlerObj((*material.Material).Search))
-		protected.POST("/delete", ginx.WrapHandlerObj((*material.Material).BatchDelete))
-		protected.POST("/update-name", ginx.WrapHandlerObj((*material.Material).UpdateMaterialName))
-		protected.POST("/publish", ginx.WrapHandlerObj((*material.Material).Publish))
-		protected.POST("/published/like", ginx.WrapHandlerObj((*material.Material).LikePublish))
-		protected.POST("/published/unlike", ginx.WrapHandlerObj((*material.Material).UnlikePublish))
+		// 修改素材需要编辑权限，点赞等互动需要浏览权限；通过API Key访问时还需具备对应的权限范围
+		readable := protected.Group("", ginx.RequireScope(apikey.ScopeMaterialRead))
+		writable := protected.Group("", ginx.RequireRole(model.RoleAdmin, model.RoleEditor), ginx.RequireScope(apikey.ScopeMaterialWrite))
+		interactive := protected.Group("", ginx.RequireRole(model.RoleAdmin, model.RoleEditor, model.RoleViewer), ginx.RequireScope(apikey.ScopeMaterialWrite))
+
+		writable.POST("/upload-token", ginx.WrapHandlerObj((*material.Material).GetUploadToken))
+		writable.POST("/save", ginx.WrapHandlerObj((*material.Material).Save))
+		writable.POST("/multipart/initiate", ginx.WrapHandlerObj((*material.Material).InitiateMultipart))
+		writable.POST("/multipart/presign", ginx.WrapHandlerObj((*material.Material).PresignMultipartParts))
+		writable.GET("/multipart/parts", ginx.WrapHandlerObj((*material.Material).ListMultipartParts))
+		writable.POST("/multipart/complete", ginx.WrapHandlerObj((*material.Material).CompleteMultipart))
+		writable.POST("/multipart/abort", ginx.WrapHandlerObj((*material.Material).AbortMultipart))
+		readable.GET("/list", ginx.WrapHandlerObj((*material.Material).List))
+		readable.GET("/search", ginx.WrapHandlerObj((*material.Material).Search))
+		readable.GET("/:id/processing", ginx.WrapHandlerObj((*material.Material).Processing))
+		readable.GET("/:id/duplicates", ginx.WrapHandlerObj((*material.Material).Duplicates))
+		readable.POST("/processing/ticket", ginx.RejectAPIKey(), ginx.WrapHandlerObj((*material.Material).StreamTicket))
+		readable.GET("/download-zip", ginx.WrapStreamObj((*material.Material).DownloadZip))
+		readable.POST("/download-zip", ginx.WrapStreamObj((*material.Material).DownloadZip))
+		writable.POST("/delete", ginx.WrapHandlerObj((*material.Material).BatchDelete))
+		readable.GET("/trash", ginx.WrapHandlerObj((*material.Material).ListTrash))
+		writable.POST("/restore", ginx.WrapHandlerObj((*material.Material).Restore))
+		writable.POST("/purge", ginx.WrapHandlerObj((*material.Material).Purge))
+		writable.POST("/update-name", ginx.WrapHandlerObj((*material.Material).UpdateMaterialName))
+		writable.POST("/move", ginx.WrapHandlerObj((*material.Material).MoveMaterials))
+
+		readable.GET("/folder/list", ginx.WrapHandlerObj((*material.Material).ListFolders))
+		readable.GET("/folder/breadcrumbs", ginx.WrapHandlerObj((*material.Material).Breadcrumbs))
+		writable.POST("/folder/create", ginx.WrapHandlerObj((*material.Material).CreateFolder))
+		writable.POST("/folder/rename", ginx.WrapHandlerObj((*material.Material).RenameFolder))
+		writable.POST("/folder/move", ginx.WrapHandlerObj((*material.Material).MoveFolder))
+		writable.POST("/folder/delete", ginx.WrapHandlerObj((*material.Material).DeleteFolder))
+
+		readable.GET("/tag/list", ginx.WrapHandlerObj((*material.Material).ListTags))
+		writable.POST("/tag/create", ginx.WrapHandlerObj((*material.Material).CreateTag))
+		writable.POST("/tag/update", ginx.WrapHandlerObj((*material.Material).UpdateTag))
+		writable.POST("/tag/delete", ginx.WrapHandlerObj((*material.Material).DeleteTag))
+		writable.POST("/tag/add", ginx.WrapHandlerObj((*material.Material).AddTags))
+		writable.POST("/tag/remove", ginx.WrapHandlerObj((*material.Material).RemoveTags))
+
+		readable.GET("/share/list", ginx.WrapHandlerObj((*material.Material).ListShares))
+		writable.POST("/share/create", ginx.WrapHandlerObj((*material.Material).CreateShare))
+		writable.POST("/share/revoke", ginx.WrapHandlerObj((*material.Material).RevokeShare))
+
+		writable.POST("/publish", ginx.WrapHandlerObj((*material.Material).Publish))
+		interactive.POST("/published/like", ginx.WrapHandlerObj((*material.Material).LikePublish))
+		interactive.POST("/published/unlike", ginx.WrapHandlerObj((*material.Material).UnlikePublish))
+	}
+
+	// 缩略图处理状态推送（SSE）；EventSource 无法设置请求头，通过 access_token 参数携带 /material/processing/ticket 签发的一次性票据
+	stream := r.Group("/material", ginx.StreamAuth("access_token"), ginx.RequireScope(apikey.ScopeMaterialRead))
+	{
+		stream.GET("/processing/stream", ginx.WrapStreamObj((*material.Material).ProcessingStream))
 	}
 
 	// 公开的路由组（无需认证）
@@ -28,4 +74,15 @@ func RegisterMaterialRouter(r *gin.Engine) {
 	{
 		public.GET("/published", ginx.WrapHandlerObj((*material.Material).ListPublished))
 	}
+
+	// 分享链接的公开访问，凭链接（及密码）访问，无需登录；有密码的分享通过请求头或 POST 请求体提交密码
+	share := r.Group("/s")
+	{
+		share.GET("/:slug", ginx.WrapHandlerObj((*material.Material).ResolveShare))
+		share.GET("/:slug/download", ginx.WrapHandlerObj((*material.Material).DownloadShare))
+		share.POST("/:slug", ginx.WrapHandlerObj((*material.Material).ResolveShare))
+		share.POST("/:slug/download", ginx.WrapHandlerObj((*material.Material).DownloadShare))
+		share.GET("/:slug/preview", ginx.WrapHandlerObj((*material.Material).PreviewShare))
+		share.POST("/:slug/preview", ginx.WrapHandlerObj((*material.Material).PreviewShare))
+	}
 }
@@ -0,0 +1,132 @@
+package material
+
+import (
+	"fmt"
+	"strconv"
+
+	"godir/internal/common/svc"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+)
+
+// 保存时最多返回的重复文件数
+const duplicateLimit = 10
+
+// saveUploaded 登记已核实的上传对象；当前用户已有内容相同的文件时在响应中列出，
+// reuseExisting 为 true 则引用最早那个文件的存储对象，删除本次上传的副本；登记成功后消费上传意图。
+// 超过同步计算上限的大文件保存时还没有哈希，重复文件通过 Duplicates 接口查询，复用由后台算出哈希后执行
+func (h *Material) saveUploaded(userID uint, fileName string, folderID *uint, obj *uploadedObject, reuseExisting bool) (*types.MaterialSaveResp, error) {
+	intent := obj.intent
+	duplicates, err := h.findDuplicates(userID, obj.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	obj.ReuseLater = reuseExisting && obj.Hash == ""
+
+	var reusedFrom uint
+	uploaded := *obj
+	if reuseExisting && len(duplicates) > 0 {
+		source := duplicates[0]
+		reusedFrom = source.ID
+		obj = &uploadedObject{
+			Bucket:      source.OssBucket,
+			Key:         source.OssFilePath,
+			Size:        obj.Size,
+			ContentType: obj.ContentType,
+			Hash:        obj.Hash,
+			CoverKey:    source.CoverOssFilePath,
+			Shared:      true,
+		}
+	}
+
+	// 登记失败时保留本次上传的对象，客户端重试时无需重新上传
+	material, err := h.createMaterial(userID, fileName, folderID, obj)
+	if err != nil {
+		return nil, err
+	}
+	h.finishUpload(intent)
+
+	// 复用了已有对象，本次上传的副本不再需要；删除失败的由孤儿对象回收兜底
+	if obj.Shared {
+		if err := svc.Minio().RemoveObject(h.Ctx, uploaded.Bucket, uploaded.Key, minio.RemoveObjectOptions{}); err != nil {
+			h.Log.Warnf("删除重复上传的对象失败, key=%s: %v", uploaded.Key, err)
+		}
+	}
+
+	list := make([]types.MaterialDuplicate, 0, len(duplicates))
+	for _, d := range duplicates {
+		list = append(list, toMaterialDuplicate(&d))
+	}
+
+	return &types.MaterialSaveResp{
+		MaterialID:  material.ID,
+		ContentHash: material.ContentHash,
+		HashPending: material.ContentHash == "",
+		Duplicates:  list,
+		ReusedFrom:  reusedFrom,
+	}, nil
+}
+
+// Duplicates 查询素材在当前用户下内容相同的其他文件，用于大文件在后台算出哈希后再提示重复
+func (h *Material) Duplicates(c *gin.Context, req *types.MaterialDuplicatesReq) (*types.MaterialDuplicatesResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		return nil, fmt.Errorf("素材ID格式错误")
+	}
+
+	var material model.GodirMaterial
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&material).Error; err != nil {
+		return nil, fmt.Errorf("素材不存在")
+	}
+
+	resp := &types.MaterialDuplicatesResp{
+		ContentHash: material.ContentHash,
+		HashPending: material.ContentHash == "",
+		Duplicates:  []types.MaterialDuplicate{},
+	}
+	duplicates, err := h.findDuplicates(userID, material.ContentHash)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range duplicates {
+		if d.ID == material.ID {
+			continue
+		}
+		resp.Duplicates = append(resp.Duplicates, toMaterialDuplicate(&d))
+	}
+	return resp, nil
+}
+
+func toMaterialDuplicate(m *model.GodirMaterial) types.MaterialDuplicate {
+	return types.MaterialDuplicate{
+		ID:        m.ID,
+		FileName:  m.FileName,
+		FolderID:  m.FolderID,
+		CreatedAt: m.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// findDuplicates 当前用户内容相同的文件（不含回收站），按创建先后排序
+func (h *Material) findDuplicates(userID uint, hash string) ([]model.GodirMaterial, error) {
+	if hash == "" {
+		return nil, nil
+	}
+
+	var materials []model.GodirMaterial
+	if err := h.DB.Where("user_id = ? AND content_hash = ?", userID, hash).
+		Order("id").
+		Limit(duplicateLimit).
+		Find(&materials).Error; err != nil {
+		return nil, fmt.Errorf("查询重复文件失败: %w", err)
+	}
+	return materials, nil
+}
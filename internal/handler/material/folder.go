@@ -0,0 +1,436 @@
+package material
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 文件夹嵌套层级上限，同时用于防止异常数据导致向上查找时死循环
+const folderMaxDepth = 64
+
+// CreateFolder 新建文件夹
+func (h *Material) CreateFolder(c *gin.Context, req *types.FolderCreateReq) (*types.FolderInfo, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := folderName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID := rootAsNil(req.ParentID)
+	if parentID != nil {
+		path, err := h.folderPath(userID, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) >= folderMaxDepth {
+			return nil, fmt.Errorf("文件夹层级过深")
+		}
+	}
+
+	folder := model.GodirFolder{UserID: userID, ParentID: parentID, Name: name}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockFolder(tx, userID, parentID); err != nil {
+			return err
+		}
+		if err := checkFolderName(tx, userID, parentID, name, 0); err != nil {
+			return err
+		}
+		if err := tx.Create(&folder).Error; err != nil {
+			return fmt.Errorf("创建文件夹失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := toFolderInfo(&folder)
+	return &info, nil
+}
+
+// RenameFolder 重命名文件夹
+func (h *Material) RenameFolder(c *gin.Context, req *types.FolderRenameReq) (*types.FolderInfo, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := folderName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := h.getFolder(userID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkFolderName(h.DB, userID, folder.ParentID, name, folder.ID); err != nil {
+		return nil, err
+	}
+
+	if err := h.DB.Model(folder).Update("name", name).Error; err != nil {
+		return nil, fmt.Errorf("重命名文件夹失败: %w", err)
+	}
+	folder.Name = name
+
+	info := toFolderInfo(folder)
+	return &info, nil
+}
+
+// MoveFolder 移动文件夹，不能移动到自身或其子文件夹下。
+// 检查和更新在同一事务中进行，并锁定被移动的文件夹及目标的整条祖先链：
+// 并发的移动会在共同的行上排队，后执行的一方读到前者已提交的结果，不会形成环
+func (h *Material) MoveFolder(c *gin.Context, req *types.FolderMoveReq) (*types.FolderInfo, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID := rootAsNil(req.ParentID)
+	var folder *model.GodirFolder
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		locked := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{})
+
+		var err error
+		if folder, err = findFolder(locked, userID, req.ID); err != nil {
+			return err
+		}
+
+		if parentID != nil {
+			// 目标文件夹的祖先链中出现自身，说明目标位于自己的子树内
+			path, err := findFolderPath(locked, userID, *parentID)
+			if err != nil {
+				return err
+			}
+			for _, f := range path {
+				if f.ID == folder.ID {
+					return fmt.Errorf("不能将文件夹移动到自身或其子文件夹中")
+				}
+			}
+			if len(path)+subtreeDepth(tx, folder.ID) > folderMaxDepth {
+				return fmt.Errorf("文件夹层级过深")
+			}
+		}
+
+		if err := checkFolderName(tx, userID, parentID, folder.Name, folder.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(folder).Update("parent_id", parentID).Error; err != nil {
+			return fmt.Errorf("移动文件夹失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	folder.ParentID = parentID
+
+	info := toFolderInfo(folder)
+	return &info, nil
+}
+
+// DeleteFolder 删除文件夹及其子文件夹，其中的素材全部移入回收站。
+// 子树在事务中逐层加锁读取；新建文件夹、移动和保存素材会先锁定目标文件夹（见 lockFolder），
+// 与删除在同一行上排队：先提交的写入会被删除读到，删除先提交则写入返回文件夹不存在
+func (h *Material) DeleteFolder(c *gin.Context, req *types.FolderDeleteReq) (*types.FolderDeleteResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var folderIDs []uint
+	var materials []model.GodirMaterial
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		locked := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Session(&gorm.Session{})
+
+		folder, err := findFolder(locked, userID, req.ID)
+		if err != nil {
+			return err
+		}
+		if folderIDs, err = subtreeIDs(locked, userID, folder.ID); err != nil {
+			return err
+		}
+
+		if err := locked.Where("user_id = ? AND folder_id IN ?", userID, folderIDs).Find(&materials).Error; err != nil {
+			return fmt.Errorf("删除文件夹失败: %w", err)
+		}
+		if len(materials) > 0 {
+			if err := tx.Where("user_id = ? AND folder_id IN ?", userID, folderIDs).Delete(&model.GodirMaterial{}).Error; err != nil {
+				return fmt.Errorf("删除文件夹失败: %w", err)
+			}
+		}
+		if err := tx.Where("user_id = ? AND id IN ?", userID, folderIDs).Delete(&model.GodirFolder{}).Error; err != nil {
+			return fmt.Errorf("删除文件夹失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	h.afterTrash(materials)
+
+	return &types.FolderDeleteResp{
+		Message: fmt.Sprintf("已删除%d个文件夹，%d个文件已移入回收站", len(folderIDs), len(materials)),
+	}, nil
+}
+
+// ListFolders 列出某个文件夹下的子文件夹，附带面包屑；parentId 不传或为0表示根目录
+func (h *Material) ListFolders(c *gin.Context, req *types.FolderListReq) (*types.FolderListResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	parentID := rootAsNil(req.ParentID)
+	breadcrumbs := []types.FolderInfo{}
+	query := h.DB.Where("user_id = ?", userID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		path, err := h.folderPath(userID, *parentID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range path {
+			breadcrumbs = append(breadcrumbs, toFolderInfo(&path[i]))
+		}
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var folders []model.GodirFolder
+	if err := query.Order("name").Find(&folders).Error; err != nil {
+		return nil, fmt.Errorf("查询文件夹失败: %w", err)
+	}
+
+	list := make([]types.FolderInfo, 0, len(folders))
+	for i := range folders {
+		list = append(list, toFolderInfo(&folders[i]))
+	}
+
+	return &types.FolderListResp{Folders: list, Breadcrumbs: breadcrumbs}, nil
+}
+
+// Breadcrumbs 从根目录到指定文件夹的路径
+func (h *Material) Breadcrumbs(c *gin.Context, req *types.FolderBreadcrumbsReq) (*types.FolderBreadcrumbsResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := h.folderPath(userID, req.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]types.FolderInfo, 0, len(path))
+	for i := range path {
+		list = append(list, toFolderInfo(&path[i]))
+	}
+	return &types.FolderBreadcrumbsResp{Breadcrumbs: list}, nil
+}
+
+// MoveMaterials 将素材移动到指定文件夹；folderId 不传或为0表示移到根目录
+func (h *Material) MoveMaterials(c *gin.Context, req *types.MaterialMoveReq) (*types.MaterialMoveResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Ids) == 0 {
+		return nil, fmt.Errorf("请选择要移动的文件")
+	}
+
+	folderID := rootAsNil(req.FolderID)
+	var count int64
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockFolder(tx, userID, folderID); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.GodirMaterial{}).Where("id IN ? AND user_id = ?", req.Ids, userID).Count(&count).Error; err != nil {
+			return fmt.Errorf("查询文件失败: %w", err)
+		}
+		if int(count) != len(uniqueIDs(req.Ids)) {
+			return fmt.Errorf("部分文件不存在或无权限移动")
+		}
+
+		if err := tx.Model(&model.GodirMaterial{}).
+			Where("id IN ? AND user_id = ?", req.Ids, userID).
+			Update("folder_id", folderID).Error; err != nil {
+			return fmt.Errorf("移动文件失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.MaterialMoveResp{Message: fmt.Sprintf("已移动%d个文件", count)}, nil
+}
+
+// checkFolder 校验文件夹属于当前用户；nil 或 0 表示根目录
+func (h *Material) checkFolder(userID uint, folderID *uint) error {
+	if rootAsNil(folderID) == nil {
+		return nil
+	}
+	_, err := h.getFolder(userID, *folderID)
+	return err
+}
+
+// lockFolder 在写入事务中锁定目标文件夹，与 DeleteFolder 互斥；nil 或 0 表示根目录，无需加锁
+func lockFolder(tx *gorm.DB, userID uint, folderID *uint) error {
+	if rootAsNil(folderID) == nil {
+		return nil
+	}
+	_, err := findFolder(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID, *folderID)
+	return err
+}
+
+func (h *Material) getFolder(userID, id uint) (*model.GodirFolder, error) {
+	return findFolder(h.DB, userID, id)
+}
+
+// folderPath 从根目录到指定文件夹的完整路径（含自身）
+func (h *Material) folderPath(userID, id uint) ([]model.GodirFolder, error) {
+	return findFolderPath(h.DB, userID, id)
+}
+
+func findFolder(db *gorm.DB, userID, id uint) (*model.GodirFolder, error) {
+	var folder model.GodirFolder
+	if err := db.Where("id = ? AND user_id = ?", id, userID).First(&folder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("文件夹不存在")
+		}
+		return nil, fmt.Errorf("查询文件夹失败: %w", err)
+	}
+	return &folder, nil
+}
+
+// findFolderPath 逐级向上查找路径，db 带锁定子句时会依次锁定路径上的每个文件夹
+func findFolderPath(db *gorm.DB, userID, id uint) ([]model.GodirFolder, error) {
+	var path []model.GodirFolder
+	next := &id
+	for next != nil {
+		if len(path) > folderMaxDepth {
+			return nil, fmt.Errorf("文件夹层级异常")
+		}
+		folder, err := findFolder(db, userID, *next)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, *folder)
+		next = folder.ParentID
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
+
+// subtreeIDs 按层广度遍历，返回文件夹自身及所有子孙文件夹的ID；db 带锁定子句时会锁定子树中的每个文件夹
+func subtreeIDs(db *gorm.DB, userID, id uint) ([]uint, error) {
+	ids := []uint{id}
+	level := []uint{id}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth > folderMaxDepth {
+			return nil, fmt.Errorf("文件夹层级异常")
+		}
+		var children []uint
+		if err := db.Model(&model.GodirFolder{}).
+			Where("user_id = ? AND parent_id IN ?", userID, level).
+			Pluck("id", &children).Error; err != nil {
+			return nil, fmt.Errorf("查询文件夹失败: %w", err)
+		}
+		ids = append(ids, children...)
+		level = children
+	}
+	return ids, nil
+}
+
+// subtreeDepth 文件夹子树的层数（只有自身时为1）
+func subtreeDepth(db *gorm.DB, id uint) int {
+	depth := 0
+	level := []uint{id}
+	for len(level) > 0 && depth <= folderMaxDepth {
+		depth++
+		var children []uint
+		if err := db.Model(&model.GodirFolder{}).Where("parent_id IN ?", level).Pluck("id", &children).Error; err != nil {
+			break
+		}
+		level = children
+	}
+	return depth
+}
+
+// checkFolderName 同一目录下不允许重名，excludeID 为正在修改的文件夹
+func checkFolderName(db *gorm.DB, userID uint, parentID *uint, name string, excludeID uint) error {
+	query := db.Model(&model.GodirFolder{}).Where("user_id = ? AND name = ? AND id <> ?", userID, name, excludeID)
+	if parentID == nil {
+		query = query.Where("parent_id IS NULL")
+	} else {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("查询文件夹失败: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("该位置已存在同名文件夹")
+	}
+	return nil
+}
+
+func folderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("文件夹名称不能为空")
+	}
+	if len(name) > 255 {
+		return "", fmt.Errorf("文件夹名称过长")
+	}
+	if strings.ContainsAny(name, "/\\") {
+		return "", fmt.Errorf("文件夹名称不能包含斜杠")
+	}
+	return name, nil
+}
+
+// rootAsNil 将 0 统一为 nil（根目录）
+func rootAsNil(id *uint) *uint {
+	if id == nil || *id == 0 {
+		return nil
+	}
+	return id
+}
+
+func uniqueIDs(ids []uint) map[uint]bool {
+	m := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		m[id] = true
+	}
+	return m
+}
+
+func toFolderInfo(f *model.GodirFolder) types.FolderInfo {
+	return types.FolderInfo{
+		ID:        f.ID,
+		Name:      f.Name,
+		ParentID:  f.ParentID,
+		CreatedAt: f.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,178 @@
+package material
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 测试用目录结构：a/b/c，以及根目录下的 d
+func setupFolders(t *testing.T) (*Material, *gin.Context, map[string]uint) {
+	t.Helper()
+	db := newTestDB(t, &model.GodirFolder{})
+
+	ids := make(map[string]uint)
+	create := func(name string, parent string) {
+		f := model.GodirFolder{UserID: 1, Name: name}
+		if parent != "" {
+			id := ids[parent]
+			f.ParentID = &id
+		}
+		if err := db.Create(&f).Error; err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = f.ID
+	}
+	create("a", "")
+	create("b", "a")
+	create("c", "b")
+	create("d", "")
+
+	h := &Material{}
+	h.DB = db
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("userId", uint(1))
+	return h, c, ids
+}
+
+func TestMoveFolderRejectsCycle(t *testing.T) {
+	h, c, ids := setupFolders(t)
+
+	for _, target := range []string{"a", "b", "c"} {
+		parent := ids[target]
+		_, err := h.MoveFolder(c, &types.FolderMoveReq{ID: ids["a"], ParentID: &parent})
+		if err == nil || !strings.Contains(err.Error(), "子文件夹") {
+			t.Errorf("移动到 %s: err = %v, want 不能移动到自身或其子文件夹中", target, err)
+		}
+	}
+
+	var a model.GodirFolder
+	if err := h.DB.First(&a, ids["a"]).Error; err != nil {
+		t.Fatal(err)
+	}
+	if a.ParentID != nil {
+		t.Fatalf("移动被拒绝后 parent_id = %d, want NULL", *a.ParentID)
+	}
+}
+
+// 被移动的文件夹和目标的整条祖先链都在事务内以 FOR UPDATE 读取
+func TestMoveFolderLocksAncestors(t *testing.T) {
+	h, c, ids := setupFolders(t)
+
+	var locked []string
+	err := h.DB.Callback().Query().Before("gorm:query").Register("test:locking", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Clauses["FOR"]; ok {
+			locked = append(locked, tx.Statement.Table)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := ids["c"]
+	info, err := h.MoveFolder(c, &types.FolderMoveReq{ID: ids["d"], ParentID: &parent})
+	if err != nil {
+		t.Fatalf("MoveFolder: %v", err)
+	}
+	if info.ParentID == nil || *info.ParentID != parent {
+		t.Fatalf("ParentID = %v, want %d", info.ParentID, parent)
+	}
+
+	// d 自身，加上 c、b、a
+	if len(locked) != 4 {
+		t.Fatalf("加锁查询 %d 次, want 4", len(locked))
+	}
+
+	var d model.GodirFolder
+	if err := h.DB.First(&d, ids["d"]).Error; err != nil {
+		t.Fatal(err)
+	}
+	if d.ParentID == nil || *d.ParentID != parent {
+		t.Fatalf("parent_id = %v, want %d", d.ParentID, parent)
+	}
+}
+
+func TestMoveFolderToRoot(t *testing.T) {
+	h, c, ids := setupFolders(t)
+
+	root := uint(0)
+	info, err := h.MoveFolder(c, &types.FolderMoveReq{ID: ids["c"], ParentID: &root})
+	if err != nil {
+		t.Fatalf("MoveFolder: %v", err)
+	}
+	if info.ParentID != nil {
+		t.Fatalf("ParentID = %d, want nil", *info.ParentID)
+	}
+}
+
+// 删除时文件夹自身、逐层的子树和其中的素材都在事务内以 FOR UPDATE 读取
+func TestDeleteFolderLocksSubtree(t *testing.T) {
+	h, c, ids := setupFolders(t)
+	if err := h.DB.AutoMigrate(&model.GodirMaterial{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var locked []string
+	err := h.DB.Callback().Query().Before("gorm:query").Register("test:locking", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Clauses["FOR"]; ok {
+			locked = append(locked, tx.Statement.Table)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.DeleteFolder(c, &types.FolderDeleteReq{ID: ids["a"]}); err != nil {
+		t.Fatalf("DeleteFolder: %v", err)
+	}
+
+	// a 自身，a、b、c 三层子文件夹查询，加上素材查询
+	if len(locked) != 5 {
+		t.Fatalf("加锁查询 %d 次 %v, want 5", len(locked), locked)
+	}
+
+	var left []string
+	if err := h.DB.Model(&model.GodirFolder{}).Order("name").Pluck("name", &left).Error; err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(left, ",") != "d" {
+		t.Fatalf("剩余文件夹 = %v, want [d]", left)
+	}
+}
+
+// 新建子文件夹先锁定父文件夹；父文件夹已删除时拒绝创建
+func TestCreateFolderLocksParent(t *testing.T) {
+	h, c, ids := setupFolders(t)
+
+	var locked int
+	err := h.DB.Callback().Query().Before("gorm:query").Register("test:locking", func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Clauses["FOR"]; ok {
+			locked++
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parent := ids["c"]
+	if _, err := h.CreateFolder(c, &types.FolderCreateReq{Name: "e", ParentID: &parent}); err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	if locked != 1 {
+		t.Fatalf("加锁查询 %d 次, want 1", locked)
+	}
+
+	if err := h.DB.Delete(&model.GodirFolder{}, parent).Error; err != nil {
+		t.Fatal(err)
+	}
+	_, err = h.CreateFolder(c, &types.FolderCreateReq{Name: "f", ParentID: &parent})
+	if err == nil || !strings.Contains(err.Error(), "文件夹不存在") {
+		t.Fatalf("err = %v, want 文件夹不存在", err)
+	}
+}
@@ -0,0 +1,201 @@
+package material
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"godir/internal/common/util/mimeutil"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"gorm.io/gorm"
+)
+
+const (
+	listDefaultLimit = 50
+	listMaxLimit     = 200
+)
+
+// listSortColumns 列表可用的排序字段与对应的数据库列
+var listSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"fileName":  "file_name",
+	"fileSize":  "file_size",
+}
+
+// listCursor 列表翻页游标，记录上一页最后一行的排序值和ID；同时记录排序方式，排序变化后旧游标失效
+type listCursor struct {
+	Sort  string          `json:"s"`
+	Order string          `json:"o"`
+	Value json.RawMessage `json:"v"`
+	ID    uint            `json:"id"`
+}
+
+// listSort 校验排序参数，返回排序字段、排序列和是否降序；默认按创建时间倒序
+func listSort(sort, order string) (string, string, bool, error) {
+	if sort == "" {
+		sort = "createdAt"
+	}
+	column, ok := listSortColumns[sort]
+	if !ok {
+		return "", "", false, fmt.Errorf("不支持的排序字段: %s", sort)
+	}
+
+	switch order {
+	case "", "desc":
+		return sort, column, true, nil
+	case "asc":
+		return sort, column, false, nil
+	}
+	return "", "", false, fmt.Errorf("不支持的排序方式: %s", order)
+}
+
+func encodeListCursor(sort, order string, value any, id uint) (string, error) {
+	v, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(listCursor{Sort: sort, Order: order, Value: v, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// listCursorValue 取素材在排序字段上的值，作为下一页游标
+func listCursorValue(sort string, m *model.GodirMaterial) any {
+	switch sort {
+	case "fileName":
+		return m.FileName
+	case "fileSize":
+		return m.FileSize
+	}
+	return m.CreatedAt
+}
+
+// decodeListCursor 解析游标，返回排序值（按排序字段还原为对应类型）和ID
+func decodeListCursor(s, sort, order string) (any, uint, error) {
+	invalid := fmt.Errorf("分页游标无效")
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, 0, invalid
+	}
+	var cur listCursor
+	if err := json.Unmarshal(b, &cur); err != nil || cur.ID == 0 {
+		return nil, 0, invalid
+	}
+	if cur.Sort != sort || cur.Order != order {
+		return nil, 0, fmt.Errorf("排序方式已变化，请从第一页重新加载")
+	}
+
+	var value any
+	switch sort {
+	case "createdAt":
+		var t time.Time
+		err = json.Unmarshal(cur.Value, &t)
+		value = t
+	case "fileName":
+		var name string
+		err = json.Unmarshal(cur.Value, &name)
+		value = name
+	case "fileSize":
+		var size int64
+		err = json.Unmarshal(cur.Value, &size)
+		value = size
+	}
+	if err != nil {
+		return nil, 0, invalid
+	}
+	return value, cur.ID, nil
+}
+
+// listFilters 列表的内容类型、日期范围和大小范围过滤条件
+func listFilters(req *types.MaterialListReq) ([]func(*gorm.DB) *gorm.DB, error) {
+	var scopes []func(*gorm.DB) *gorm.DB
+
+	if req.Family != "" {
+		scope, err := familyScope(req.Family)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+
+	if req.CreatedFrom != "" {
+		from, _, err := parseListDate(req.CreatedFrom)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("created_at >= ?", from)
+		})
+	}
+	if req.CreatedTo != "" {
+		to, dateOnly, err := parseListDate(req.CreatedTo)
+		if err != nil {
+			return nil, err
+		}
+		// 只给日期时包含当天
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+			scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+				return db.Where("created_at < ?", to)
+			})
+		} else {
+			scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+				return db.Where("created_at <= ?", to)
+			})
+		}
+	}
+
+	if req.MinSize != nil && req.MaxSize != nil && *req.MinSize > *req.MaxSize {
+		return nil, fmt.Errorf("文件大小范围不合法")
+	}
+	if req.MinSize != nil {
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("file_size >= ?", *req.MinSize)
+		})
+	}
+	if req.MaxSize != nil {
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Where("file_size <= ?", *req.MaxSize)
+		})
+	}
+
+	return scopes, nil
+}
+
+// familyScope 按素材分类过滤，规则与 mimeutil.Family 保持一致
+func familyScope(family string) (func(*gorm.DB) *gorm.DB, error) {
+	const document = "(content_type LIKE 'text/%' OR content_type IN ('application/pdf', 'application/json') " +
+		"OR content_type LIKE 'application/msword%' OR content_type LIKE 'application/vnd.%')"
+
+	var cond string
+	switch family {
+	case mimeutil.FamilyImage, mimeutil.FamilyVideo, mimeutil.FamilyAudio:
+		cond = fmt.Sprintf("content_type LIKE '%s/%%'", family)
+	case mimeutil.FamilyDocument:
+		cond = document
+	case mimeutil.FamilyOther:
+		cond = "NOT (content_type LIKE 'image/%' OR content_type LIKE 'video/%' OR content_type LIKE 'audio/%' OR " + document + ")"
+	default:
+		return nil, fmt.Errorf("不支持的文件分类: %s", family)
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(cond)
+	}, nil
+}
+
+// parseListDate 解析日期过滤参数，支持日期或日期时间，返回是否只给了日期
+func parseListDate(s string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, fmt.Errorf("日期格式错误: %s", s)
+}
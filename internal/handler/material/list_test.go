@@ -0,0 +1,223 @@
+package material
+
+import (
+	"encoding/base64"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"godir/internal/model"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestListCursorRoundTrip(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 30, 45, 123456789, time.Local)
+
+	tests := []struct {
+		sort  string
+		value any
+	}{
+		{"createdAt", created},
+		{"fileName", "报告 2026（终稿）.pdf"},
+		{"fileSize", int64(5 << 40)},
+	}
+
+	for _, tt := range tests {
+		for _, order := range []string{"asc", "desc"} {
+			cursor, err := encodeListCursor(tt.sort, order, tt.value, 42)
+			if err != nil {
+				t.Fatalf("%s %s: encode: %v", tt.sort, order, err)
+			}
+			value, id, err := decodeListCursor(cursor, tt.sort, order)
+			if err != nil {
+				t.Fatalf("%s %s: decode: %v", tt.sort, order, err)
+			}
+			if id != 42 {
+				t.Errorf("%s %s: id = %d, want 42", tt.sort, order, id)
+			}
+
+			if want, ok := tt.value.(time.Time); ok {
+				got, ok := value.(time.Time)
+				if !ok || !got.Equal(want) {
+					t.Errorf("%s %s: value = %v, want %v", tt.sort, order, value, want)
+				}
+				continue
+			}
+			if value != tt.value {
+				t.Errorf("%s %s: value = %#v, want %#v", tt.sort, order, value, tt.value)
+			}
+		}
+	}
+}
+
+// 游标只对生成它的排序方式有效
+func TestDecodeListCursorRejectsSortChange(t *testing.T) {
+	cursor, err := encodeListCursor("fileName", "asc", "a.txt", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct{ sort, order string }{
+		{"fileName", "desc"},
+		{"fileSize", "asc"},
+		{"createdAt", "asc"},
+	} {
+		_, _, err := decodeListCursor(cursor, tt.sort, tt.order)
+		if err == nil || !strings.Contains(err.Error(), "排序方式已变化") {
+			t.Errorf("%s %s: err = %v, want 排序方式已变化", tt.sort, tt.order, err)
+		}
+	}
+}
+
+func TestDecodeListCursorRejectsMalformed(t *testing.T) {
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+	}{
+		{"非base64", "not a cursor!", "createdAt"},
+		{"非JSON", enc("hello"), "createdAt"},
+		{"缺少ID", enc(`{"s":"fileSize","o":"desc","v":10}`), "fileSize"},
+		{"ID为0", enc(`{"s":"fileSize","o":"desc","v":10,"id":0}`), "fileSize"},
+		{"大小不是数字", enc(`{"s":"fileSize","o":"desc","v":"big","id":1}`), "fileSize"},
+		{"文件名不是字符串", enc(`{"s":"fileName","o":"desc","v":1,"id":1}`), "fileName"},
+		{"时间格式错误", enc(`{"s":"createdAt","o":"desc","v":"yesterday","id":1}`), "createdAt"},
+		{"标准base64填充", base64.StdEncoding.EncodeToString([]byte(`{"s":"fileSize","o":"desc","v":1,"id":1}`)), "fileSize"},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := decodeListCursor(tt.cursor, tt.sort, "desc"); err == nil {
+			t.Errorf("%s: 应返回错误", tt.name)
+		}
+	}
+}
+
+// 逐页翻完全部数据：每种排序字段和方向下，排序值相同的行按ID确定先后，不重复不遗漏
+func TestListCursorPagination(t *testing.T) {
+	db := newTestDB(t, &model.GodirMaterial{})
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var rows []model.GodirMaterial
+	for i := 0; i < 17; i++ {
+		rows = append(rows, model.GodirMaterial{
+			UserID:   1,
+			FileName: []string{"a.txt", "b.txt", "c.txt"}[i%3], // 大量重复的排序值
+			FileSize: int64(i % 4 * 100),
+			Base:     model.Base{Model: gorm.Model{CreatedAt: base.Add(time.Duration(i%5) * time.Hour)}},
+		})
+	}
+	// 其他用户的数据不应出现在结果中
+	rows = append(rows, model.GodirMaterial{UserID: 2, FileName: "a.txt", Base: model.Base{Model: gorm.Model{CreatedAt: base}}})
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	owned := func(db *gorm.DB) *gorm.DB { return db.Where("user_id = ?", 1) }
+
+	for sortField := range listSortColumns {
+		for _, order := range []string{"asc", "desc"} {
+			_, column, desc, err := listSort(sortField, order)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := make([]model.GodirMaterial, 0, len(rows))
+			for _, r := range rows {
+				if r.UserID == 1 {
+					want = append(want, r)
+				}
+			}
+			sort.Slice(want, func(i, j int) bool {
+				c := compareListValue(listCursorValue(sortField, &want[i]), listCursorValue(sortField, &want[j]))
+				if c == 0 {
+					c = int(want[i].ID) - int(want[j].ID)
+				}
+				if desc {
+					return c > 0
+				}
+				return c < 0
+			})
+
+			var got []uint
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(want) {
+					t.Fatalf("%s %s: 翻页未结束", sortField, order)
+				}
+
+				page := model.CursorPage{SortColumn: column, Desc: desc, Limit: 4}
+				if cursor != "" {
+					page.AfterValue, page.AfterID, err = decodeListCursor(cursor, sortField, order)
+					if err != nil {
+						t.Fatalf("%s %s: decode: %v", sortField, order, err)
+					}
+				}
+
+				var list []model.GodirMaterial
+				total, hasMore, err := model.NewGodirMaterial(db).FindCursorPage(&list, page, owned)
+				if err != nil {
+					t.Fatalf("%s %s: %v", sortField, order, err)
+				}
+				if total != int64(len(want)) {
+					t.Fatalf("%s %s: total = %d, want %d", sortField, order, total, len(want))
+				}
+				for _, m := range list {
+					got = append(got, m.ID)
+				}
+				if !hasMore {
+					break
+				}
+
+				last := list[len(list)-1]
+				if cursor, err = encodeListCursor(sortField, order, listCursorValue(sortField, &last), last.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("%s %s: 共取到%d条, want %d", sortField, order, len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i].ID {
+					t.Fatalf("%s %s: 第%d条 id = %d, want %d（got %v）", sortField, order, i, got[i], want[i].ID, got)
+				}
+			}
+		}
+	}
+}
+
+// newTestDB 内存中的 SQLite 数据库，建好指定的表
+func newTestDB(t *testing.T, models ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func compareListValue(a, b any) int {
+	switch a := a.(type) {
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	case int64:
+		switch bb := b.(int64); {
+		case a < bb:
+			return -1
+		case a > bb:
+			return 1
+		}
+	}
+	return 0
+}
@@ -2,6 +2,8 @@ package material
 
 import (
 	"bytes"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -9,8 +11,13 @@ import (
 	"strings"
 	"time"
 
+	"godir/internal/common/esx"
+	"godir/internal/common/exterr"
 	"godir/internal/common/ginx"
 	"godir/internal/common/jwt"
+	"godir/internal/common/miniox"
+	"godir/internal/common/objref"
+	"godir/internal/common/quota"
 	"godir/internal/common/redis"
 	"godir/internal/common/svc"
 	"godir/internal/common/util/pathutil"
@@ -18,6 +25,8 @@ import (
 	"godir/internal/types"
 
 	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"gorm.io/gorm"
 )
 
 type Material struct {
@@ -41,44 +50,82 @@ func (h *Material) GetUploadToken(c *gin.Context, req *types.MaterialUploadToken
 		return nil, fmt.Errorf("用户ID格式错误")
 	}
 
+	// 预检查配额，保存时会按实际大小再次校验
+	if err := quota.Check(h.DB, userIDUint, req.FileSize); err != nil {
+		return nil, err
+	}
+
 	cfg := svc.Cfg()
 
-	// 生成文件key（使用用户ID和时间戳）
-	ext := filepath.Ext(req.FileName)
-	key := fmt.Sprintf("user/%d/%d%s", userIDUint, time.Now().UnixNano(), ext)
+	// 由服务端生成文件key，客户端只能上传到该key
+	key := newUploadKey(userIDUint, req.FileName)
 
-	// cred, err := miniox.TemporaryCredentials(cfg, []string{key})
-	// if err != nil {
-	// 	h.Log.Errorf("生成临时凭证失败: %v", err)
-	// 	return nil, exterr.Fail
-	// }
+	// 生成只允许上传该对象的临时凭证
+	cred, err := miniox.TemporaryCredentials(cfg, []string{key})
+	if err != nil {
+		h.Log.Errorf("生成临时凭证失败: %v", err)
+		return nil, exterr.Fail
+	}
 
-	// return &types.MaterialUploadTokenResp{
-	// 	AccessKeyID:     cred.AccessKeyID,
-	// 	SecretAccessKey: cred.SecretAccessKey,
-	// 	SessionToken:    cred.SessionToken,
-	// 	Bucket:          cfg.MinIO.Bucket,
-	// 	Key:             key,
-	// 	Endpoint:        miniox.BuildBaseUrl(cfg.MinIO.UseSSL, cfg.MinIO.Endpoint),
-	// }, nil
-
-	// 生成临时访问凭证
-	// 注意：MinIO本身不支持STS，这里直接返回主凭证
-	// 生产环境应该使用STS服务或自定义临时凭证生成
-	protocol := "http"
-	if cfg.MinIO.UseSSL {
-		protocol = "https"
+	// 登记上传意图，Save 时只接受本人申请过的key；凭证过期后留出一段时间完成保存
+	intent := &redis.UploadIntent{
+		UserID:      userIDUint,
+		Bucket:      cfg.MinIO.Bucket,
+		Key:         key,
+		FileName:    req.FileName,
+		FileSize:    req.FileSize,
+		ContentType: req.ContentType,
+		CreatedAt:   time.Now().Unix(),
 	}
+	if err := redis.SaveUploadIntent(h.Ctx, intent, time.Until(cred.Expiration)+uploadIntentGrace); err != nil {
+		h.Log.Errorf("登记上传意图失败: %v", err)
+		return nil, exterr.Fail
+	}
+
 	return &types.MaterialUploadTokenResp{
-		AccessKeyID:     cfg.MinIO.AccessKeyID,
-		SecretAccessKey: cfg.MinIO.SecretAccessKey,
-		SessionToken:    "", // MinIO不支持session token
+		AccessKeyID:     cred.AccessKeyID,
+		SecretAccessKey: cred.SecretAccessKey,
+		SessionToken:    cred.SessionToken,
 		Bucket:          cfg.MinIO.Bucket,
 		Key:             key,
-		Endpoint:        fmt.Sprintf("%s://%s", protocol, cfg.MinIO.Endpoint),
+		Endpoint:        miniox.BuildBaseUrl(cfg.MinIO.UseSSL, cfg.MinIO.Endpoint),
+		ExpiresAt:       cred.Expiration.Format("2006-01-02 15:04:05"),
+		ExpiresIn:       int64(time.Until(cred.Expiration).Seconds()),
 	}, nil
 }
 
+// 客户端上传到暂存前缀，保存时由服务端复制到正式前缀；临时凭证只授予暂存key的写权限
+const (
+	uploadKeyPrefix = "upload/"
+	objectKeyPrefix = "user/"
+)
+
+// newUploadKey 生成客户端上传用的暂存key：upload/<用户ID>/<日期>/<随机串><扩展名>
+func newUploadKey(userID uint, fileName string) string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s%d/%s/%s%s", uploadKeyPrefix, userID, time.Now().Format("20060102"), hex.EncodeToString(b), safeExt(fileName))
+}
+
+// objectKeyFor 暂存key对应的正式对象key：user/<用户ID>/<日期>/<随机串><扩展名>
+func objectKeyFor(uploadKey string) string {
+	return objectKeyPrefix + strings.TrimPrefix(uploadKey, uploadKeyPrefix)
+}
+
+// safeExt 取文件扩展名，只保留字母数字，避免把客户端传入的特殊字符带进key
+func safeExt(fileName string) string {
+	ext := strings.ToLower(filepath.Ext(fileName))
+	if len(ext) < 2 || len(ext) > 16 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
 // Save 保存文件信息
 func (h *Material) Save(c *gin.Context, req *types.MaterialSaveReq) (*types.MaterialSaveResp, error) {
 	// 从上下文获取用户ID
@@ -92,66 +139,25 @@ func (h *Material) Save(c *gin.Context, req *types.MaterialSaveReq) (*types.Mate
 		return nil, fmt.Errorf("用户ID格式错误")
 	}
 
-	// 创建文件记录
-	material := model.GodirMaterial{
-		UserID:      userIDUint,
-		FileName:    req.FileName,
-		FileExt:     pathutil.Ext(req.FileName),
-		FileSize:    req.FileSize,
-		ContentType: req.ContentType,
-		OssBucket:   req.Bucket,
-		OssFilePath: req.Key,
+	if err := h.checkFolder(userIDUint, req.FolderID); err != nil {
+		return nil, err
 	}
 
-	// if err := material.Save(&material); err != nil {
-	// 	return nil, fmt.Errorf("保存文件信息失败: %w", err)
-	// }0
-
-	if err := h.DB.Create(&material).Error; err != nil {
-		return nil, fmt.Errorf("保存文件信息失败: %w", err)
+	unlock, err := h.lockUpload(req.Key)
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	// 索引到 Elasticsearch
-	esClient := svc.ES()
-	if esClient != nil {
-		// 构造索引文档
-		doc := map[string]interface{}{
-			"id":                  material.ID,
-			"user_id":             material.UserID,
-			"file_name":           material.FileName,
-			"file_size":           material.FileSize,
-			"content_type":        material.ContentType,
-			"oss_bucket":          material.OssBucket,
-			"oss_file_path":       material.OssFilePath,
-			"cover_oss_file_path": material.CoverOssFilePath,
-			"created_at":          material.CreatedAt,
-		}
-
-		b, err := json.Marshal(doc)
-		if err != nil {
-			h.Log.Warnf("ES 索引序列化失败: %v", err)
-		} else {
-			resp, err := esClient.Index(
-				"godir_material",
-				bytes.NewReader(b),
-				esClient.Index.WithDocumentID(fmt.Sprintf("%d", material.ID)),
-			)
-			if err != nil {
-				h.Log.Warnf("ES索引失败: %v", err)
-			} else if resp != nil {
-				defer resp.Body.Close()
-			}
-		}
+	// 校验对象确实由本人通过 GetUploadToken 申请并已上传，大小和类型以服务端读取的为准
+	obj, err := h.verifyUpload(userIDUint, req.Bucket, req.Key, "", req.FileSize, req.ContentType)
+	if err != nil {
+		return nil, err
 	}
 
-	err := redis.PushThumbnailTask(&redis.ThumbnailTask{
-		MaterialID:  material.ID,
-		Bucket:      req.Bucket,
-		Key:         req.Key,
-		ContentType: req.ContentType,
-	})
+	resp, err := h.saveUploaded(userIDUint, req.FileName, req.FolderID, obj, req.ReuseExisting)
 	if err != nil {
-		h.Log.Warnf("推送缩略图任务失败: %v", err)
+		return nil, err
 	}
 
 	// // generate thumbnail for image/video using ffmpeg, upload to MinIO and save cover info
@@ -215,7 +221,88 @@ func (h *Material) Save(c *gin.Context, req *types.MaterialSaveReq) (*types.Mate
 	// 	}
 	// }
 
-	return &types.MaterialSaveResp{MaterialID: material.ID}, nil
+	return resp, nil
+}
+
+// createMaterial 为已核实的上传对象创建素材记录，写入ES索引并投递缩略图任务
+func (h *Material) createMaterial(userID uint, fileName string, folderID *uint, obj *uploadedObject) (*model.GodirMaterial, error) {
+	// 创建文件记录
+	material := model.GodirMaterial{
+		UserID:           userID,
+		FolderID:         rootAsNil(folderID),
+		FileName:         fileName,
+		FileExt:          pathutil.Ext(fileName),
+		FileSize:         obj.Size,
+		ContentType:      obj.ContentType,
+		OssBucket:        obj.Bucket,
+		OssFilePath:      obj.Key,
+		CoverOssFilePath: obj.CoverKey,
+		ContentHash:      obj.Hash,
+	}
+
+	// if err := material.Save(&material); err != nil {
+	// 	return nil, fmt.Errorf("保存文件信息失败: %w", err)
+	// }0
+
+	// 记录与存储用量在同一事务中写入，超出配额时整体回滚并删除已上传的对象；
+	// 配额按素材的文件大小计算（与 quota 对账一致），复用已有对象的素材同样计入，去重节省的是存储而不是配额
+	// 同时锁定目标文件夹，避免文件夹在保存过程中被删除而留下无主的记录
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := lockFolder(tx, userID, material.FolderID); err != nil {
+			return err
+		}
+		if err := quota.Reserve(tx, userID, material.FileSize); err != nil {
+			return err
+		}
+		if err := tx.Create(&material).Error; err != nil {
+			return fmt.Errorf("保存文件信息失败: %w", err)
+		}
+		if err := objref.Acquire(tx, obj.Bucket, obj.Key, obj.Hash, obj.Size); err != nil {
+			return fmt.Errorf("保存文件信息失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// 复用的对象仍被其他素材引用，不能删除
+		if exterr.Code(err) == exterr.CodeQuotaExceeded && !obj.Shared {
+			if err := svc.Minio().RemoveObject(h.Ctx, obj.Bucket, obj.Key, minio.RemoveObjectOptions{}); err != nil {
+				h.Log.Warnf("删除超出配额的对象失败: %v", err)
+			}
+		}
+		return nil, err
+	}
+
+	// 索引到 Elasticsearch
+	if err := esx.IndexMaterial(h.Ctx, &material); err != nil {
+		h.Log.Warnf("ES索引失败: %v", err)
+	}
+
+	if material.CoverOssFilePath == "" {
+		err = redis.PushThumbnailTask(&redis.ThumbnailTask{
+			MaterialID:  material.ID,
+			UserID:      material.UserID,
+			Bucket:      material.OssBucket,
+			Key:         material.OssFilePath,
+			ContentType: material.ContentType,
+		})
+		if err != nil {
+			h.Log.Warnf("推送缩略图任务失败: %v", err)
+		}
+	}
+
+	if material.ContentHash == "" {
+		err = redis.PushHashTask(h.Ctx, &redis.HashTask{
+			MaterialID:    material.ID,
+			Bucket:        material.OssBucket,
+			Key:           material.OssFilePath,
+			ReuseExisting: obj.ReuseLater,
+		})
+		if err != nil {
+			h.Log.Warnf("推送哈希任务失败: %v", err)
+		}
+	}
+
+	return &material, nil
 }
 
 // List 获取文件列表
@@ -231,13 +318,78 @@ func (h *Material) List(c *gin.Context, req *types.MaterialListReq) (*types.Mate
 		return nil, fmt.Errorf("用户ID格式错误")
 	}
 
+	sort, column, desc, err := listSort(req.Sort, req.Order)
+	if err != nil {
+		return nil, err
+	}
+	order := "asc"
+	if desc {
+		order = "desc"
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = listDefaultLimit
+	}
+	if limit > listMaxLimit {
+		limit = listMaxLimit
+	}
+
+	page := model.CursorPage{SortColumn: column, Desc: desc, Limit: limit}
+	if req.Cursor != "" {
+		page.AfterValue, page.AfterID, err = decodeListCursor(req.Cursor, sort, order)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scopes, err := listFilters(req)
+	if err != nil {
+		return nil, err
+	}
+	scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", userIDUint)
+	})
+
+	// 未指定 folderId 时返回全部素材；folderId=0 表示根目录
+	if req.FolderID != nil {
+		folderID := *req.FolderID
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			if folderID == 0 {
+				return db.Where("folder_id IS NULL")
+			}
+			return db.Where("folder_id = ?", folderID)
+		})
+	}
+
+	if len(req.TagIDs) > 0 {
+		scope, err := tagScope(h.DB, req.TagIDs, req.TagMode)
+		if err != nil {
+			return nil, err
+		}
+		scopes = append(scopes, scope)
+	}
+
 	var materials []model.GodirMaterial
-	if err := h.DB.Where("user_id = ?", userIDUint).
-		Order("created_at DESC").
-		Find(&materials).Error; err != nil {
+	total, hasMore, err := model.NewGodirMaterial(h.DB).FindCursorPage(&materials, page, scopes...)
+	if err != nil {
 		return nil, fmt.Errorf("查询文件列表失败: %w", err)
 	}
 
+	nextCursor := ""
+	if hasMore {
+		last := materials[len(materials)-1]
+		if nextCursor, err = encodeListCursor(sort, order, listCursorValue(sort, &last), last.ID); err != nil {
+			return nil, fmt.Errorf("生成分页游标失败: %w", err)
+		}
+	}
+
+	materialIDs := make([]uint, 0, len(materials))
+	for _, m := range materials {
+		materialIDs = append(materialIDs, m.ID)
+	}
+	tags := h.materialTags(materialIDs)
+
 	materialList := make([]types.MaterialInfo, 0, len(materials))
 	for _, m := range materials {
 		// 生成预签名URL
@@ -301,11 +453,15 @@ func (h *Material) List(c *gin.Context, req *types.MaterialListReq) (*types.Mate
 			DownloadURL:     downloadURL,
 			PreviewURL:      previewURL,
 			CreatedAt:       m.CreatedAt.Format("2006-01-02 15:04:05"),
+			FolderID:        m.FolderID,
+			Tags:            tags[m.ID],
 		})
 	}
 
 	return &types.MaterialListResp{
-		Materials: materialList,
+		Materials:  materialList,
+		NextCursor: nextCursor,
+		Total:      total,
 	}, nil
 }
 
@@ -322,22 +478,31 @@ func (h *Material) Search(c *gin.Context, req *types.MaterialSearchReq) (*types.
 	}
 
 	q := strings.TrimSpace(req.Q)
-	if q == "" {
+	if q == "" && len(req.TagIDs) == 0 {
 		return &types.MaterialSearchResp{Materials: []types.MaterialInfo{}}, nil
 	}
 
+	tagFilters, err := esTagFilters(req.TagIDs, req.TagMode)
+	if err != nil {
+		return nil, err
+	}
+
 	var results []types.MaterialInfo
 
 	esClient := svc.ES()
 	if esClient != nil {
-		// 使用 ES 搜索，按文件名前缀匹配并过滤当前用户
+		// 使用 ES 搜索，按文件名前缀匹配并过滤当前用户，可叠加标签过滤
+		must := []interface{}{
+			map[string]interface{}{"term": map[string]interface{}{"user_id": userIDUint}},
+		}
+		if q != "" {
+			must = append(must, map[string]interface{}{"match_phrase_prefix": map[string]interface{}{"file_name": map[string]interface{}{"query": q}}})
+		}
 		body := map[string]interface{}{
 			"query": map[string]interface{}{
 				"bool": map[string]interface{}{
-					"must": []interface{}{
-						map[string]interface{}{"match_phrase_prefix": map[string]interface{}{"file_name": map[string]interface{}{"query": q}}},
-						map[string]interface{}{"term": map[string]interface{}{"user_id": userIDUint}},
-					},
+					"must":   must,
+					"filter": append([]interface{}{}, tagFilters...),
 				},
 			},
 			"size": 50,
@@ -445,6 +610,15 @@ func (h *Material) Search(c *gin.Context, req *types.MaterialSearchReq) (*types.
 	// 	}
 	// }
 
+	resultIDs := make([]uint, 0, len(results))
+	for _, mi := range results {
+		resultIDs = append(resultIDs, mi.ID)
+	}
+	tags := h.materialTags(resultIDs)
+	for i := range results {
+		results[i].Tags = tags[results[i].ID]
+	}
+
 	return &types.MaterialSearchResp{Materials: results}, nil
 }
 
@@ -580,14 +754,25 @@ func (h *Material) BatchDelete(c *gin.Context, req *types.MaterialBatchDeleteReq
 		}
 	}
 
-	// 从数据库中删除记录（注意：不删除MinIO中的实际文件）
 	if err := h.DB.Where("id IN (?)", req.Ids).Delete(&model.GodirMaterial{}).Error; err != nil {
 		return nil, fmt.Errorf("删除文件记录失败: %w", err)
 	}
+	h.afterTrash(materials)
 
 	return &types.MaterialBatchDeleteResp{}, nil
 }
 
+// afterTrash 素材软删除（移入回收站）后调用；文件在彻底删除或超过保留期后才清理，
+// 但回收站中的素材不出现在搜索结果里，异步删除ES文档（不带对象key，只删文档）
+func (h *Material) afterTrash(materials []model.GodirMaterial) {
+	for _, material := range materials {
+		err := redis.PushCleanupTask(h.Ctx, &redis.CleanupTask{MaterialID: material.ID})
+		if err != nil {
+			h.Log.Warnf("推送清理任务失败, material_id=%d: %v", material.ID, err)
+		}
+	}
+}
+
 // ListPublished 获取发布列表
 func (h *Material) ListPublished(c *gin.Context, req *types.PublishListReq) (*types.PublishListResp, error) {
 	var publishedMaterials []model.GodirPublishedMaterial
@@ -0,0 +1,335 @@
+package material
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"godir/internal/common/miniox"
+	"godir/internal/common/quota"
+	"godir/internal/common/redis"
+	"godir/internal/common/svc"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	multipartMinPartSize  = 16 << 20 // 16MiB，S3要求除最后一片外不小于5MiB
+	multipartMaxParts     = 10000
+	multipartMaxFileSize  = 5 << 40 // 5TiB
+	multipartPresignBatch = 100     // 单次最多签发的分片URL数量
+	multipartIntentTTL    = 24 * time.Hour
+)
+
+// InitiateMultipart 发起分片上传，返回 uploadId、服务端生成的key和分片大小
+func (h *Material) InitiateMultipart(c *gin.Context, req *types.MultipartInitiateReq) (*types.MultipartInitiateResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.FileSize <= 0 || req.FileSize > multipartMaxFileSize {
+		return nil, fmt.Errorf("文件大小不合法")
+	}
+
+	if err := quota.Check(h.DB, userID, req.FileSize); err != nil {
+		return nil, err
+	}
+
+	if err := h.checkFolder(userID, req.FolderID); err != nil {
+		return nil, err
+	}
+
+	cfg := svc.Cfg()
+	key := newUploadKey(userID, req.FileName)
+	partSize := multipartPartSize(req.FileSize)
+
+	core := minio.Core{Client: svc.Minio()}
+	uploadID, err := core.NewMultipartUpload(h.Ctx, cfg.MinIO.Bucket, key, minio.PutObjectOptions{ContentType: req.ContentType})
+	if err != nil {
+		h.Log.Errorf("发起分片上传失败: %v", err)
+		return nil, fmt.Errorf("发起分片上传失败")
+	}
+
+	intent := &redis.UploadIntent{
+		UserID:      userID,
+		Bucket:      cfg.MinIO.Bucket,
+		Key:         key,
+		FileName:    req.FileName,
+		FileSize:    req.FileSize,
+		ContentType: req.ContentType,
+		CreatedAt:   time.Now().Unix(),
+		UploadID:    uploadID,
+		PartSize:    partSize,
+		FolderID:    req.FolderID,
+	}
+	if err := redis.SaveUploadIntent(h.Ctx, intent, multipartIntentTTL); err != nil {
+		h.Log.Errorf("登记上传意图失败: %v", err)
+		_ = core.AbortMultipartUpload(h.Ctx, cfg.MinIO.Bucket, key, uploadID)
+		return nil, fmt.Errorf("发起分片上传失败")
+	}
+
+	return &types.MultipartInitiateResp{
+		UploadID:  uploadID,
+		Bucket:    cfg.MinIO.Bucket,
+		Key:       key,
+		PartSize:  partSize,
+		PartCount: partCount(req.FileSize, partSize),
+		ExpiresAt: time.Now().Add(multipartIntentTTL).Format("2006-01-02 15:04:05"),
+	}, nil
+}
+
+// PresignMultipartParts 为指定分片签发上传URL，客户端可分批获取，过期后重新获取即可
+func (h *Material) PresignMultipartParts(c *gin.Context, req *types.MultipartPresignReq) (*types.MultipartPresignResp, error) {
+	intent, err := h.multipartIntent(c, req.Key, req.UploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.PartNumbers) == 0 || len(req.PartNumbers) > multipartPresignBatch {
+		return nil, fmt.Errorf("单次可获取1~%d个分片", multipartPresignBatch)
+	}
+
+	total := partCount(intent.FileSize, intent.PartSize)
+	exp := miniox.UploadTokenExp(svc.Cfg())
+	parts := make([]types.MultipartPartURL, 0, len(req.PartNumbers))
+	for _, n := range req.PartNumbers {
+		if n < 1 || n > total {
+			return nil, fmt.Errorf("分片序号超出范围: %d", n)
+		}
+
+		params := url.Values{}
+		params.Set("partNumber", strconv.Itoa(n))
+		params.Set("uploadId", intent.UploadID)
+		u, err := svc.Minio().Presign(h.Ctx, "PUT", intent.Bucket, intent.Key, exp, params)
+		if err != nil {
+			h.Log.Errorf("签发分片URL失败: %v", err)
+			return nil, fmt.Errorf("签发分片URL失败")
+		}
+		parts = append(parts, types.MultipartPartURL{PartNumber: n, URL: u.String()})
+	}
+
+	return &types.MultipartPresignResp{
+		Parts:     parts,
+		ExpiresAt: time.Now().Add(exp).Format("2006-01-02 15:04:05"),
+	}, nil
+}
+
+// ListMultipartParts 列出已上传的分片，供断点续传时跳过
+func (h *Material) ListMultipartParts(c *gin.Context, req *types.MultipartPartsReq) (*types.MultipartPartsResp, error) {
+	intent, err := h.multipartIntent(c, req.Key, req.UploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	uploaded, err := h.listUploadedParts(intent)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]types.MultipartPart, 0, len(uploaded))
+	for _, p := range uploaded {
+		parts = append(parts, types.MultipartPart{PartNumber: p.PartNumber, ETag: p.ETag, Size: p.Size})
+	}
+
+	return &types.MultipartPartsResp{
+		Parts:     parts,
+		PartSize:  intent.PartSize,
+		PartCount: partCount(intent.FileSize, intent.PartSize),
+	}, nil
+}
+
+// CompleteMultipart 以服务端列出的分片完成合并，随后按普通上传的流程校验并登记素材。
+// 可重复调用：合并成功但登记失败时重试只做登记，已登记过的直接返回上次的结果
+func (h *Material) CompleteMultipart(c *gin.Context, req *types.MultipartCompleteReq) (*types.MaterialSaveResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先加锁再查询结果和意图：正在登记的请求持有锁直到结果写入，重试不会落在两者之间
+	unlock, err := h.lockUpload(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	result, err := redis.GetUploadResult(h.Ctx, req.Key)
+	if err != nil {
+		h.Log.Errorf("查询上传结果失败: %v", err)
+		return nil, fmt.Errorf("查询上传状态失败")
+	}
+	if result != nil && result.UserID == userID && result.UploadID == req.UploadID {
+		return &types.MaterialSaveResp{
+			MaterialID:  result.MaterialID,
+			ContentHash: result.ContentHash,
+			ReusedFrom:  result.ReusedFrom,
+		}, nil
+	}
+
+	intent, err := h.multipartIntent(c, req.Key, req.UploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 上次合并成功但登记失败时对象已存在，直接重试登记
+	if _, err := svc.Minio().StatObject(h.Ctx, intent.Bucket, intent.Key, minio.StatObjectOptions{}); err == nil {
+		return h.saveMultipart(intent, req.ReuseExisting)
+	}
+
+	uploaded, err := h.listUploadedParts(intent)
+	if err != nil {
+		return nil, err
+	}
+
+	// 分片必须从1开始连续且总大小与声明一致
+	total := partCount(intent.FileSize, intent.PartSize)
+	if len(uploaded) != total {
+		return nil, fmt.Errorf("分片未全部上传：已上传%d/%d", len(uploaded), total)
+	}
+	var size int64
+	parts := make([]minio.CompletePart, 0, len(uploaded))
+	for i, p := range uploaded {
+		if p.PartNumber != i+1 {
+			return nil, fmt.Errorf("缺少分片%d", i+1)
+		}
+		size += p.Size
+		parts = append(parts, minio.CompletePart{PartNumber: p.PartNumber, ETag: p.ETag})
+	}
+	if size != intent.FileSize {
+		return nil, fmt.Errorf("文件大小与发起上传时不一致")
+	}
+
+	core := minio.Core{Client: svc.Minio()}
+	if _, err := core.CompleteMultipartUpload(h.Ctx, intent.Bucket, intent.Key, intent.UploadID, parts, minio.PutObjectOptions{}); err != nil {
+		h.Log.Errorf("合并分片失败: %v", err)
+		return nil, fmt.Errorf("合并分片失败")
+	}
+
+	return h.saveMultipart(intent, req.ReuseExisting)
+}
+
+// saveMultipart 合并完成后走与 Save 相同的校验和登记流程，并记录结果供重试时返回
+func (h *Material) saveMultipart(intent *redis.UploadIntent, reuseExisting bool) (*types.MaterialSaveResp, error) {
+	obj, err := h.verifyUpload(intent.UserID, intent.Bucket, intent.Key, intent.UploadID, intent.FileSize, intent.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := h.saveUploaded(intent.UserID, intent.FileName, intent.FolderID, obj, reuseExisting)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &redis.UploadResult{
+		UserID:      intent.UserID,
+		UploadID:    intent.UploadID,
+		MaterialID:  resp.MaterialID,
+		ContentHash: resp.ContentHash,
+		ReusedFrom:  resp.ReusedFrom,
+	}
+	if err := redis.SaveUploadResult(h.Ctx, intent.Key, result); err != nil {
+		h.Log.Warnf("记录上传结果失败, key=%s: %v", intent.Key, err)
+	}
+	return resp, nil
+}
+
+// AbortMultipart 放弃分片上传，释放已上传的分片
+func (h *Material) AbortMultipart(c *gin.Context, req *types.MultipartAbortReq) (*types.MultipartAbortResp, error) {
+	intent, err := h.multipartIntent(c, req.Key, req.UploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	core := minio.Core{Client: svc.Minio()}
+	if err := core.AbortMultipartUpload(h.Ctx, intent.Bucket, intent.Key, intent.UploadID); err != nil {
+		if minio.ToErrorResponse(err).Code != "NoSuchUpload" {
+			h.Log.Errorf("取消分片上传失败: %v", err)
+			return nil, fmt.Errorf("取消分片上传失败")
+		}
+	}
+
+	if _, err := redis.ConsumeUploadIntent(h.Ctx, intent.Key); err != nil {
+		h.Log.Warnf("作废上传意图失败: %v", err)
+	}
+
+	return &types.MultipartAbortResp{Message: "已取消上传"}, nil
+}
+
+// multipartIntent 读取并校验当前用户的分片上传意图
+func (h *Material) multipartIntent(c *gin.Context, key, uploadID string) (*redis.UploadIntent, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	intent, err := redis.GetUploadIntent(h.Ctx, key)
+	if err != nil {
+		h.Log.Errorf("查询上传意图失败: %v", err)
+		return nil, fmt.Errorf("查询上传状态失败")
+	}
+	if intent == nil || intent.UserID != userID || intent.UploadID == "" || intent.UploadID != uploadID {
+		return nil, fmt.Errorf("分片上传不存在或已过期，请重新上传")
+	}
+	return intent, nil
+}
+
+// listUploadedParts 分页列出全部已上传分片，按序号升序
+func (h *Material) listUploadedParts(intent *redis.UploadIntent) ([]minio.ObjectPart, error) {
+	core := minio.Core{Client: svc.Minio()}
+
+	var parts []minio.ObjectPart
+	marker := 0
+	for {
+		result, err := core.ListObjectParts(h.Ctx, intent.Bucket, intent.Key, intent.UploadID, marker, 1000)
+		if err != nil {
+			if minio.ToErrorResponse(err).Code == "NoSuchUpload" {
+				return nil, fmt.Errorf("分片上传不存在或已过期，请重新上传")
+			}
+			h.Log.Errorf("列出已上传分片失败: %v", err)
+			return nil, fmt.Errorf("查询上传状态失败")
+		}
+		parts = append(parts, result.ObjectParts...)
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+// multipartPartSize 分片大小：不小于16MiB，且保证分片数不超过10000，按MiB对齐
+func multipartPartSize(fileSize int64) int64 {
+	size := int64(multipartMinPartSize)
+	if need := (fileSize + multipartMaxParts - 1) / multipartMaxParts; need > size {
+		size = (need + 1<<20 - 1) / (1 << 20) * (1 << 20)
+	}
+	return size
+}
+
+func partCount(fileSize, partSize int64) int {
+	if partSize <= 0 {
+		return 0
+	}
+	return int((fileSize + partSize - 1) / partSize)
+}
+
+// currentUserID 从上下文获取当前用户ID
+func currentUserID(c *gin.Context) (uint, error) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		return 0, fmt.Errorf("未登录")
+	}
+
+	userIDUint, ok := userID.(uint)
+	if !ok {
+		return 0, fmt.Errorf("用户ID格式错误")
+	}
+	return userIDUint, nil
+}
@@ -0,0 +1,171 @@
+package material
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"godir/internal/common/ginx"
+	"godir/internal/common/jwt"
+	"godir/internal/common/redis"
+	"godir/internal/common/svc"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 推送流的心跳间隔，避免代理因连接空闲而断开
+const processingHeartbeat = 25 * time.Second
+
+// Processing 查询素材的缩略图处理状态
+func (h *Material) Processing(c *gin.Context, req *types.MaterialProcessingReq) (*types.MaterialProcessingResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		return nil, fmt.Errorf("素材ID格式错误")
+	}
+
+	var material model.GodirMaterial
+	if err := h.DB.Where("id = ? AND user_id = ?", id, userID).First(&material).Error; err != nil {
+		return nil, fmt.Errorf("素材不存在")
+	}
+
+	status, err := redis.GetThumbnailStatus(h.Ctx, material.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询处理状态失败: %w", err)
+	}
+	if status == nil {
+		// 状态记录已过期或素材早于状态跟踪，按封面是否已生成推断
+		status = &redis.ThumbnailStatus{MaterialID: material.ID, State: redis.ThumbnailUnknown}
+		if material.CoverOssFilePath != "" {
+			status.State = redis.ThumbnailDone
+		} else if !strings.HasPrefix(material.ContentType, "image/") && !strings.HasPrefix(material.ContentType, "video/") {
+			status.State = redis.ThumbnailSkipped
+		}
+	}
+
+	resp := toProcessingResp(status)
+	if status.State == redis.ThumbnailDone {
+		resp.CoverPreviewURL = h.coverPreviewURL(&material)
+	}
+	return resp, nil
+}
+
+// StreamTicket 签发连接推送流的一次性票据；EventSource 无法设置请求头，前端每次建立连接前先获取票据
+func (h *Material) StreamTicket(c *gin.Context, req *types.MaterialStreamTicketReq) (*types.MaterialStreamTicketResp, error) {
+	userInfo, exists := c.Get("userInfo")
+	if !exists {
+		return nil, fmt.Errorf("未登录")
+	}
+	claims, ok := userInfo.(jwt.Claims)
+	if !ok {
+		return nil, fmt.Errorf("用户信息格式错误")
+	}
+
+	ticket, err := jwt.IssueStreamTicket(h.Ctx, &claims)
+	if err != nil {
+		return nil, fmt.Errorf("签发票据失败: %w", err)
+	}
+	return &types.MaterialStreamTicketResp{
+		Ticket:    ticket,
+		ExpiresIn: int64(jwt.StreamTicketTTL / time.Second),
+	}, nil
+}
+
+// ProcessingStream 以 SSE 推送当前用户素材的缩略图状态变化，事件名为 status，数据格式同 Processing；
+// 状态经 Redis pub/sub 分发，连接到任意API实例都能收到
+func (h *Material) ProcessingStream(c *gin.Context, req *types.MaterialProcessingStreamReq) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	sub := redis.SubscribeThumbnailStatus(h.Ctx, userID)
+	defer sub.Close()
+	// 确认订阅成功后再开始写响应，失败时仍能按普通接口返回错误
+	if _, err := sub.Receive(h.Ctx); err != nil {
+		return fmt.Errorf("订阅处理状态失败: %w", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 关闭 nginx 的响应缓冲
+	c.Status(http.StatusOK)
+	_, _ = c.Writer.WriteString(": connected\n\n")
+	c.Writer.Flush()
+
+	events := sub.Channel()
+	heartbeat := time.NewTicker(processingHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-h.Ctx.Done():
+			return nil
+		case <-ginx.StreamsClosed():
+			return nil
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				return nil
+			}
+		case msg, ok := <-events:
+			if !ok {
+				return nil
+			}
+			var status redis.ThumbnailStatus
+			if err := json.Unmarshal([]byte(msg.Payload), &status); err != nil {
+				h.Log.Warnf("解析缩略图状态失败: %v", err)
+				continue
+			}
+
+			resp := toProcessingResp(&status)
+			if status.State == redis.ThumbnailDone {
+				var material model.GodirMaterial
+				if err := h.DB.Where("id = ? AND user_id = ?", status.MaterialID, userID).First(&material).Error; err == nil {
+					resp.CoverPreviewURL = h.coverPreviewURL(&material)
+				}
+			}
+			c.SSEvent("status", resp)
+		}
+		c.Writer.Flush()
+	}
+}
+
+// coverPreviewURL 生成封面的预签名预览地址，与列表接口一致
+func (h *Material) coverPreviewURL(m *model.GodirMaterial) string {
+	minioClient := svc.Minio()
+	if minioClient == nil || m.CoverOssFilePath == "" {
+		return ""
+	}
+
+	params := make(map[string][]string)
+	params["response-content-disposition"] = []string{"inline"}
+	u, err := minioClient.PresignedGetObject(h.Ctx, m.OssBucket, m.CoverOssFilePath, time.Hour*24*7, params)
+	if err != nil {
+		h.Log.Warnf("生成封面预览地址失败: %v", err)
+		return ""
+	}
+	return u.String()
+}
+
+func toProcessingResp(status *redis.ThumbnailStatus) *types.MaterialProcessingResp {
+	resp := &types.MaterialProcessingResp{
+		MaterialID: status.MaterialID,
+		State:      status.State,
+		Error:      status.Error,
+		Attempts:   status.Attempts,
+	}
+	if status.UpdatedAt > 0 {
+		resp.UpdatedAt = time.Unix(status.UpdatedAt, 0).Format("2006-01-02 15:04:05")
+	}
+	return resp
+}
@@ -0,0 +1,474 @@
+package material
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"godir/internal/common/exterr"
+	"godir/internal/common/svc"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// 分享页签发的预签名URL有效期
+	shareURLExpiry = 15 * time.Minute
+
+	// 在线预览地址有效期，只需覆盖打开页面的时间，缩短被转存后当作下载地址使用的窗口
+	sharePreviewExpiry = 5 * time.Minute
+
+	// 同一IP对同一链接的密码错误次数限制
+	sharePasswordMaxFails   = 10
+	sharePasswordFailWindow = 15 * time.Minute
+
+	shareTypeMaterial = "material"
+	shareTypeFolder   = "folder"
+)
+
+// CreateShare 为素材或文件夹创建分享链接
+func (h *Material) CreateShare(c *gin.Context, req *types.ShareCreateReq) (*types.ShareLinkInfo, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if (req.MaterialID == 0) == (req.FolderID == 0) {
+		return nil, fmt.Errorf("请选择要分享的文件或文件夹")
+	}
+	if req.ExpiresIn < 0 || req.MaxDownloads < 0 {
+		return nil, fmt.Errorf("参数错误")
+	}
+
+	link := model.GodirShareLink{
+		UserID:        userID,
+		MaxDownloads:  req.MaxDownloads,
+		AllowPreview:  req.AllowPreview == nil || *req.AllowPreview,
+		AllowDownload: req.AllowDownload == nil || *req.AllowDownload,
+	}
+	if !link.AllowPreview && !link.AllowDownload {
+		return nil, fmt.Errorf("预览和下载至少允许一项")
+	}
+
+	targetName := ""
+	if req.MaterialID != 0 {
+		var material model.GodirMaterial
+		if err := h.DB.Where("id = ? AND user_id = ?", req.MaterialID, userID).First(&material).Error; err != nil {
+			return nil, fmt.Errorf("文件不存在")
+		}
+		link.MaterialID = &material.ID
+		targetName = material.FileName
+	} else {
+		folder, err := h.getFolder(userID, req.FolderID)
+		if err != nil {
+			return nil, err
+		}
+		link.FolderID = &folder.ID
+		targetName = folder.Name
+	}
+
+	if req.Password != "" {
+		if len(req.Password) > 72 {
+			return nil, fmt.Errorf("密码过长")
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("创建分享失败: %w", err)
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	if req.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+		link.ExpiresAt = &expiresAt
+	}
+
+	link.Slug, err = newShareSlug()
+	if err != nil {
+		return nil, fmt.Errorf("创建分享失败: %w", err)
+	}
+	if err := h.DB.Create(&link).Error; err != nil {
+		return nil, fmt.Errorf("创建分享失败: %w", err)
+	}
+
+	info := toShareLinkInfo(&link, targetName)
+	return &info, nil
+}
+
+// ListShares 当前用户创建的分享链接（不含已撤销的）
+func (h *Material) ListShares(c *gin.Context, req *types.ShareListReq) (*types.ShareListResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []model.GodirShareLink
+	if err := h.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("查询分享失败: %w", err)
+	}
+
+	var materialIDs, folderIDs []uint
+	for _, l := range links {
+		if l.MaterialID != nil {
+			materialIDs = append(materialIDs, *l.MaterialID)
+		}
+		if l.FolderID != nil {
+			folderIDs = append(folderIDs, *l.FolderID)
+		}
+	}
+
+	names := make(map[string]string)
+	if len(materialIDs) > 0 {
+		var materials []model.GodirMaterial
+		if err := h.DB.Select("id", "file_name").Where("id IN ?", materialIDs).Find(&materials).Error; err != nil {
+			return nil, fmt.Errorf("查询分享失败: %w", err)
+		}
+		for _, m := range materials {
+			names[fmt.Sprintf("%s:%d", shareTypeMaterial, m.ID)] = m.FileName
+		}
+	}
+	if len(folderIDs) > 0 {
+		var folders []model.GodirFolder
+		if err := h.DB.Select("id", "name").Where("id IN ?", folderIDs).Find(&folders).Error; err != nil {
+			return nil, fmt.Errorf("查询分享失败: %w", err)
+		}
+		for _, f := range folders {
+			names[fmt.Sprintf("%s:%d", shareTypeFolder, f.ID)] = f.Name
+		}
+	}
+
+	list := make([]types.ShareLinkInfo, 0, len(links))
+	for i := range links {
+		typ, id := shareTarget(&links[i])
+		list = append(list, toShareLinkInfo(&links[i], names[fmt.Sprintf("%s:%d", typ, id)]))
+	}
+	return &types.ShareListResp{List: list}, nil
+}
+
+// RevokeShare 撤销分享链接，撤销后立即无法访问
+func (h *Material) RevokeShare(c *gin.Context, req *types.ShareRevokeReq) (*types.ShareRevokeResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	result := h.DB.Where("id IN ? AND user_id = ?", req.Ids, userID).Delete(&model.GodirShareLink{})
+	if result.Error != nil {
+		return nil, fmt.Errorf("撤销分享失败: %w", result.Error)
+	}
+	return &types.ShareRevokeResp{Message: fmt.Sprintf("已撤销%d个分享", result.RowsAffected)}, nil
+}
+
+// ResolveShare 公开访问分享链接，返回分享内容；允许预览时附带短时有效的封面缩略图
+func (h *Material) ResolveShare(c *gin.Context, req *types.ShareResolveReq) (*types.ShareResolveResp, error) {
+	link, err := h.openShare(c, req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &types.ShareResolveResp{
+		AllowPreview:       link.AllowPreview,
+		AllowDownload:      link.AllowDownload,
+		RemainingDownloads: -1,
+	}
+	if link.ExpiresAt != nil {
+		resp.ExpiresAt = link.ExpiresAt.Format("2006-01-02 15:04:05")
+	}
+	if link.MaxDownloads > 0 {
+		resp.RemainingDownloads = max(link.MaxDownloads-link.DownloadCount, 0)
+	}
+
+	if link.MaterialID != nil {
+		material, err := h.shareMaterial(link, *link.MaterialID)
+		if err != nil {
+			return nil, err
+		}
+		item := h.toShareItem(c, link, material)
+		resp.Type = shareTypeMaterial
+		resp.Name = material.FileName
+		resp.Material = &item
+		return resp, nil
+	}
+
+	// 文件夹分享可浏览其下的子文件夹
+	current := *link.FolderID
+	if req.FolderID != 0 {
+		current = req.FolderID
+	}
+	path, err := h.folderPath(link.UserID, current)
+	if err != nil {
+		return nil, fmt.Errorf("分享的文件夹不存在")
+	}
+	root := -1
+	for i, f := range path {
+		if f.ID == *link.FolderID {
+			root = i
+			break
+		}
+	}
+	if root < 0 {
+		return nil, fmt.Errorf("分享的文件夹不存在")
+	}
+	resp.Type = shareTypeFolder
+	resp.Name = path[root].Name
+	resp.Breadcrumbs = make([]types.FolderInfo, 0, len(path)-root)
+	for i := root; i < len(path); i++ {
+		resp.Breadcrumbs = append(resp.Breadcrumbs, toFolderInfo(&path[i]))
+	}
+
+	var folders []model.GodirFolder
+	if err := h.DB.Where("user_id = ? AND parent_id = ?", link.UserID, current).Order("name").Find(&folders).Error; err != nil {
+		return nil, fmt.Errorf("查询分享内容失败: %w", err)
+	}
+	resp.Folders = make([]types.FolderInfo, 0, len(folders))
+	for i := range folders {
+		resp.Folders = append(resp.Folders, toFolderInfo(&folders[i]))
+	}
+
+	var materials []model.GodirMaterial
+	if err := h.DB.Where("user_id = ? AND folder_id = ?", link.UserID, current).Order("file_name").Find(&materials).Error; err != nil {
+		return nil, fmt.Errorf("查询分享内容失败: %w", err)
+	}
+	resp.Materials = make([]types.ShareItem, 0, len(materials))
+	for i := range materials {
+		resp.Materials = append(resp.Materials, h.toShareItem(c, link, &materials[i]))
+	}
+
+	return resp, nil
+}
+
+// DownloadShare 通过分享链接下载文件，计入下载次数并返回短时有效的下载地址。
+// 下载次数统计的是签发地址的次数，返回的地址在 shareURLExpiry 内可重复使用，并不对应实际下载次数；
+// 只允许预览的分享通过 PreviewShare 在线查看
+func (h *Material) DownloadShare(c *gin.Context, req *types.ShareDownloadReq) (*types.ShareDownloadResp, error) {
+	link, err := h.openShare(c, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !link.AllowDownload {
+		return nil, fmt.Errorf("该分享不允许下载")
+	}
+
+	material, err := h.shareTargetMaterial(link, req.MaterialID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 条件更新保证并发下载不会超过次数限制
+	result := h.DB.Model(&model.GodirShareLink{}).
+		Where("id = ? AND (max_downloads = 0 OR download_count < max_downloads)", link.ID).
+		UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+	if result.Error != nil {
+		return nil, fmt.Errorf("下载失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("该分享的下载次数已用完")
+	}
+
+	params := url.Values{}
+	if req.Inline {
+		params.Set("response-content-disposition", "inline")
+	} else {
+		params.Set("response-content-disposition", fmt.Sprintf("attachment; filename=\"%s\"", material.FileName))
+	}
+	u, err := svc.Minio().PresignedGetObject(c.Request.Context(), material.OssBucket, material.OssFilePath, shareURLExpiry, params)
+	if err != nil {
+		h.Log.Errorf("生成下载地址失败: %v", err)
+		return nil, fmt.Errorf("下载失败")
+	}
+
+	return &types.ShareDownloadResp{URL: u.String(), ExpiresIn: int64(shareURLExpiry.Seconds())}, nil
+}
+
+// PreviewShare 在线查看分享的原文件，需分享允许预览，不要求允许下载，也不占用下载次数；
+// 返回 inline 方式的短时地址，没有封面的文档、音频等也能查看，每次签发计入预览次数
+func (h *Material) PreviewShare(c *gin.Context, req *types.SharePreviewReq) (*types.SharePreviewResp, error) {
+	link, err := h.openShare(c, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !link.AllowPreview {
+		return nil, fmt.Errorf("该分享不允许预览")
+	}
+
+	material, err := h.shareTargetMaterial(link, req.MaterialID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.DB.Model(&model.GodirShareLink{}).Where("id = ?", link.ID).
+		UpdateColumn("view_count", gorm.Expr("view_count + 1")).Error; err != nil {
+		h.Log.Warnf("记录分享预览次数失败: %v", err)
+	}
+
+	params := url.Values{}
+	params.Set("response-content-disposition", "inline")
+	u, err := svc.Minio().PresignedGetObject(c.Request.Context(), material.OssBucket, material.OssFilePath, sharePreviewExpiry, params)
+	if err != nil {
+		h.Log.Errorf("生成预览地址失败: %v", err)
+		return nil, fmt.Errorf("预览失败")
+	}
+
+	return &types.SharePreviewResp{URL: u.String(), ExpiresIn: int64(sharePreviewExpiry.Seconds())}, nil
+}
+
+// shareTargetMaterial 确定下载或预览的素材：单个素材的分享即其本身，文件夹分享须指定其中的素材
+func (h *Material) shareTargetMaterial(link *model.GodirShareLink, materialID uint) (*model.GodirMaterial, error) {
+	if link.MaterialID != nil {
+		if materialID != 0 && materialID != *link.MaterialID {
+			return nil, fmt.Errorf("文件不存在")
+		}
+		materialID = *link.MaterialID
+	}
+	if materialID == 0 {
+		return nil, fmt.Errorf("请选择文件")
+	}
+	return h.shareMaterial(link, materialID)
+}
+
+// openShare 按路径中的 slug 查找有效的分享链接并校验密码；password 来自 POST 请求体，请求头 X-Share-Password 优先
+func (h *Material) openShare(c *gin.Context, password string) (*model.GodirShareLink, error) {
+	var link model.GodirShareLink
+	if err := h.DB.Where("slug = ?", c.Param("slug")).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("分享链接不存在或已失效")
+		}
+		return nil, fmt.Errorf("查询分享失败: %w", err)
+	}
+	if link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt) {
+		return nil, fmt.Errorf("分享链接已过期")
+	}
+
+	if link.PasswordHash == "" {
+		return &link, nil
+	}
+
+	if p := c.GetHeader("X-Share-Password"); p != "" {
+		password = p
+	}
+	if password == "" {
+		return nil, exterr.New(exterr.CodeSharePassword, "请输入分享密码")
+	}
+
+	failKey := fmt.Sprintf("share:pwfail:%s:%s", link.Slug, c.ClientIP())
+	fails, err := svc.Redis().Get(h.Ctx, failKey).Int()
+	if err == nil && fails >= sharePasswordMaxFails {
+		return nil, fmt.Errorf("密码错误次数过多，请稍后再试")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)); err != nil {
+		pipe := svc.Redis().TxPipeline()
+		pipe.Incr(h.Ctx, failKey)
+		pipe.Expire(h.Ctx, failKey, sharePasswordFailWindow)
+		if _, err := pipe.Exec(h.Ctx); err != nil {
+			h.Log.Warnf("记录分享密码错误失败: %v", err)
+		}
+		return nil, exterr.New(exterr.CodeSharePassword, "分享密码错误")
+	}
+	return &link, nil
+}
+
+// shareMaterial 查询分享范围内的素材：单个素材的分享即其本身，文件夹分享则须位于该文件夹（含子文件夹）中
+func (h *Material) shareMaterial(link *model.GodirShareLink, materialID uint) (*model.GodirMaterial, error) {
+	var material model.GodirMaterial
+	if err := h.DB.Where("id = ? AND user_id = ?", materialID, link.UserID).First(&material).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("分享的文件不存在或已删除")
+		}
+		return nil, fmt.Errorf("查询分享内容失败: %w", err)
+	}
+
+	if link.FolderID != nil {
+		if material.FolderID == nil {
+			return nil, fmt.Errorf("分享的文件不存在或已删除")
+		}
+		path, err := h.folderPath(link.UserID, *material.FolderID)
+		if err != nil {
+			return nil, fmt.Errorf("分享的文件不存在或已删除")
+		}
+		inside := false
+		for _, f := range path {
+			if f.ID == *link.FolderID {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			return nil, fmt.Errorf("分享的文件不存在或已删除")
+		}
+	}
+	return &material, nil
+}
+
+// toShareItem 分享页中的文件信息；允许预览时附带封面缩略图，原文件通过 PreviewShare 在线查看
+func (h *Material) toShareItem(c *gin.Context, link *model.GodirShareLink, m *model.GodirMaterial) types.ShareItem {
+	item := types.ShareItem{
+		ID:          m.ID,
+		FileName:    m.FileName,
+		FileSize:    m.FileSize,
+		ContentType: m.ContentType,
+		CreatedAt:   m.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if !link.AllowPreview {
+		return item
+	}
+
+	inline := url.Values{}
+	inline.Set("response-content-disposition", "inline")
+	if m.CoverOssFilePath != "" {
+		if u, err := svc.Minio().PresignedGetObject(c.Request.Context(), m.OssBucket, m.CoverOssFilePath, shareURLExpiry, inline); err == nil {
+			item.CoverURL = u.String()
+		}
+	}
+	return item
+}
+
+func shareTarget(link *model.GodirShareLink) (string, uint) {
+	if link.MaterialID != nil {
+		return shareTypeMaterial, *link.MaterialID
+	}
+	if link.FolderID != nil {
+		return shareTypeFolder, *link.FolderID
+	}
+	return "", 0
+}
+
+func toShareLinkInfo(link *model.GodirShareLink, targetName string) types.ShareLinkInfo {
+	typ, id := shareTarget(link)
+	info := types.ShareLinkInfo{
+		ID:            link.ID,
+		Slug:          link.Slug,
+		Path:          "/s/" + link.Slug,
+		Type:          typ,
+		TargetID:      id,
+		TargetName:    targetName,
+		HasPassword:   link.PasswordHash != "",
+		MaxDownloads:  link.MaxDownloads,
+		DownloadCount: link.DownloadCount,
+		ViewCount:     link.ViewCount,
+		AllowPreview:  link.AllowPreview,
+		AllowDownload: link.AllowDownload,
+		CreatedAt:     link.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if link.ExpiresAt != nil {
+		info.ExpiresAt = link.ExpiresAt.Format("2006-01-02 15:04:05")
+		info.Expired = time.Now().After(*link.ExpiresAt)
+	}
+	return info
+}
+
+// newShareSlug 生成分享链接的随机标识（12位 URL 安全字符）
+func newShareSlug() (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,328 @@
+package material
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"godir/internal/common/esx"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 标签过滤模式：and 要求同时带有全部标签，or 带有任一标签即可
+const (
+	TagModeAnd = "and"
+	TagModeOr  = "or"
+)
+
+// ListTags 当前用户的全部标签及各标签下的素材数量（不含回收站）
+func (h *Material) ListTags(c *gin.Context, req *types.TagListReq) (*types.TagListResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []model.GodirTag
+	if err := h.DB.Where("user_id = ?", userID).Order("name").Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("查询标签失败: %w", err)
+	}
+
+	var counts []struct {
+		TagID uint
+		Count int64
+	}
+	if err := h.DB.Table("godir_material_tag AS mt").
+		Select("mt.tag_id, COUNT(*) AS count").
+		Joins("JOIN godir_material AS m ON m.id = mt.material_id AND m.deleted_at IS NULL").
+		Where("m.user_id = ?", userID).
+		Group("mt.tag_id").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("统计标签失败: %w", err)
+	}
+	countMap := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		countMap[c.TagID] = c.Count
+	}
+
+	list := make([]types.TagInfo, 0, len(tags))
+	for i := range tags {
+		info := toTagInfo(&tags[i])
+		info.MaterialCount = countMap[tags[i].ID]
+		list = append(list, info)
+	}
+	return &types.TagListResp{Tags: list}, nil
+}
+
+// CreateTag 新建标签
+func (h *Material) CreateTag(c *gin.Context, req *types.TagCreateReq) (*types.TagInfo, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := tagName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.checkTagName(userID, name, 0); err != nil {
+		return nil, err
+	}
+
+	tag := model.GodirTag{UserID: userID, Name: name, Color: strings.TrimSpace(req.Color)}
+	if err := h.DB.Create(&tag).Error; err != nil {
+		return nil, fmt.Errorf("创建标签失败: %w", err)
+	}
+
+	info := toTagInfo(&tag)
+	return &info, nil
+}
+
+// UpdateTag 修改标签名称或颜色
+func (h *Material) UpdateTag(c *gin.Context, req *types.TagUpdateReq) (*types.TagInfo, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := tagName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var tag model.GodirTag
+	if err := h.DB.Where("id = ? AND user_id = ?", req.ID, userID).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("标签不存在")
+		}
+		return nil, fmt.Errorf("查询标签失败: %w", err)
+	}
+	if err := h.checkTagName(userID, name, tag.ID); err != nil {
+		return nil, err
+	}
+
+	// ES 中只存标签ID，改名不需要重建索引
+	tag.Name = name
+	tag.Color = strings.TrimSpace(req.Color)
+	if err := h.DB.Model(&tag).Select("name", "color").Updates(&tag).Error; err != nil {
+		return nil, fmt.Errorf("修改标签失败: %w", err)
+	}
+
+	info := toTagInfo(&tag)
+	return &info, nil
+}
+
+// DeleteTag 删除标签，并从所有素材上移除
+func (h *Material) DeleteTag(c *gin.Context, req *types.TagDeleteReq) (*types.TagDeleteResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := h.userTags(userID, []uint{req.ID}); err != nil {
+		return nil, err
+	}
+
+	var materialIDs []uint
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.GodirMaterialTag{}).Where("tag_id = ?", req.ID).Pluck("material_id", &materialIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tag_id = ?", req.ID).Delete(&model.GodirMaterialTag{}).Error; err != nil {
+			return err
+		}
+		// 硬删除，释放同名标签的唯一索引
+		return tx.Unscoped().Where("id = ? AND user_id = ?", req.ID, userID).Delete(&model.GodirTag{}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("删除标签失败: %w", err)
+	}
+	h.reindexTags(materialIDs)
+
+	return &types.TagDeleteResp{Message: fmt.Sprintf("已删除标签，%d个文件受影响", len(materialIDs))}, nil
+}
+
+// AddTags 批量为素材添加标签，已有的关联忽略
+func (h *Material) AddTags(c *gin.Context, req *types.MaterialTagsReq) (*types.MaterialTagsResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.checkTagTargets(userID, req.MaterialIDs, req.TagIDs); err != nil {
+		return nil, err
+	}
+
+	links := make([]model.GodirMaterialTag, 0, len(req.MaterialIDs)*len(req.TagIDs))
+	for materialID := range uniqueIDs(req.MaterialIDs) {
+		for tagID := range uniqueIDs(req.TagIDs) {
+			links = append(links, model.GodirMaterialTag{MaterialID: materialID, TagID: tagID})
+		}
+	}
+	if err := h.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error; err != nil {
+		return nil, fmt.Errorf("添加标签失败: %w", err)
+	}
+	h.reindexTags(req.MaterialIDs)
+
+	return &types.MaterialTagsResp{Message: fmt.Sprintf("已为%d个文件添加标签", len(uniqueIDs(req.MaterialIDs)))}, nil
+}
+
+// RemoveTags 批量移除素材上的标签
+func (h *Material) RemoveTags(c *gin.Context, req *types.MaterialTagsReq) (*types.MaterialTagsResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.checkTagTargets(userID, req.MaterialIDs, req.TagIDs); err != nil {
+		return nil, err
+	}
+
+	if err := h.DB.Where("material_id IN ? AND tag_id IN ?", req.MaterialIDs, req.TagIDs).
+		Delete(&model.GodirMaterialTag{}).Error; err != nil {
+		return nil, fmt.Errorf("移除标签失败: %w", err)
+	}
+	h.reindexTags(req.MaterialIDs)
+
+	return &types.MaterialTagsResp{Message: fmt.Sprintf("已为%d个文件移除标签", len(uniqueIDs(req.MaterialIDs)))}, nil
+}
+
+// checkTagTargets 校验素材和标签都属于当前用户
+func (h *Material) checkTagTargets(userID uint, materialIDs, tagIDs []uint) error {
+	if len(materialIDs) == 0 {
+		return fmt.Errorf("请选择文件")
+	}
+	if len(tagIDs) == 0 {
+		return fmt.Errorf("请选择标签")
+	}
+
+	var count int64
+	if err := h.DB.Model(&model.GodirMaterial{}).Where("id IN ? AND user_id = ?", materialIDs, userID).Count(&count).Error; err != nil {
+		return fmt.Errorf("查询文件失败: %w", err)
+	}
+	if int(count) != len(uniqueIDs(materialIDs)) {
+		return fmt.Errorf("部分文件不存在或无权限操作")
+	}
+
+	_, err := h.userTags(userID, tagIDs)
+	return err
+}
+
+// userTags 查询当前用户的指定标签，有任一不存在时报错
+func (h *Material) userTags(userID uint, ids []uint) ([]model.GodirTag, error) {
+	var tags []model.GodirTag
+	if err := h.DB.Where("id IN ? AND user_id = ?", ids, userID).Find(&tags).Error; err != nil {
+		return nil, fmt.Errorf("查询标签失败: %w", err)
+	}
+	if len(tags) != len(uniqueIDs(ids)) {
+		return nil, fmt.Errorf("标签不存在")
+	}
+	return tags, nil
+}
+
+func (h *Material) checkTagName(userID uint, name string, excludeID uint) error {
+	var count int64
+	if err := h.DB.Model(&model.GodirTag{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, excludeID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("查询标签失败: %w", err)
+	}
+	if count > 0 {
+		return fmt.Errorf("标签已存在")
+	}
+	return nil
+}
+
+// reindexTags 标签关联变化后更新ES文档中的 tag_ids
+func (h *Material) reindexTags(materialIDs []uint) {
+	if err := esx.ReindexMaterials(h.Ctx, materialIDs); err != nil {
+		h.Log.Warnf("ES重建素材索引失败: %v", err)
+	}
+}
+
+// materialTags 批量查询素材上的标签
+func (h *Material) materialTags(materialIDs []uint) map[uint][]types.TagInfo {
+	result := make(map[uint][]types.TagInfo)
+	if len(materialIDs) == 0 {
+		return result
+	}
+
+	var rows []struct {
+		MaterialID uint
+		model.GodirTag
+	}
+	if err := h.DB.Table("godir_material_tag AS mt").
+		Select("mt.material_id, t.*").
+		Joins("JOIN godir_tag AS t ON t.id = mt.tag_id AND t.deleted_at IS NULL").
+		Where("mt.material_id IN ?", materialIDs).
+		Order("t.name").
+		Scan(&rows).Error; err != nil {
+		h.Log.Warnf("查询素材标签失败: %v", err)
+		return result
+	}
+
+	for i := range rows {
+		result[rows[i].MaterialID] = append(result[rows[i].MaterialID], toTagInfo(&rows[i].GodirTag))
+	}
+	return result
+}
+
+// tagScope 按标签过滤素材查询的条件
+func tagScope(db *gorm.DB, tagIDs []uint, mode string) (func(*gorm.DB) *gorm.DB, error) {
+	sub := db.Model(&model.GodirMaterialTag{}).Select("material_id").Where("tag_id IN ?", tagIDs)
+	switch mode {
+	case "", TagModeOr:
+	case TagModeAnd:
+		sub = sub.Group("material_id").Having("COUNT(DISTINCT tag_id) = ?", len(uniqueIDs(tagIDs)))
+	default:
+		return nil, fmt.Errorf("不支持的标签过滤模式: %s", mode)
+	}
+	return func(query *gorm.DB) *gorm.DB {
+		return query.Where("id IN (?)", sub)
+	}, nil
+}
+
+// esTagFilters 按标签过滤的ES查询条件
+func esTagFilters(tagIDs []uint, mode string) ([]interface{}, error) {
+	if len(tagIDs) == 0 {
+		return nil, nil
+	}
+
+	switch mode {
+	case "", TagModeOr:
+		return []interface{}{
+			map[string]interface{}{"terms": map[string]interface{}{"tag_ids": tagIDs}},
+		}, nil
+	case TagModeAnd:
+		filters := make([]interface{}, 0, len(tagIDs))
+		for _, id := range tagIDs {
+			filters = append(filters, map[string]interface{}{"term": map[string]interface{}{"tag_ids": id}})
+		}
+		return filters, nil
+	default:
+		return nil, fmt.Errorf("不支持的标签过滤模式: %s", mode)
+	}
+}
+
+func tagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("标签名称不能为空")
+	}
+	if len([]rune(name)) > 50 {
+		return "", fmt.Errorf("标签名称过长")
+	}
+	return name, nil
+}
+
+func toTagInfo(t *model.GodirTag) types.TagInfo {
+	return types.TagInfo{
+		ID:    t.ID,
+		Name:  t.Name,
+		Color: t.Color,
+	}
+}
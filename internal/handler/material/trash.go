@@ -0,0 +1,142 @@
+package material
+
+import (
+	"fmt"
+	"time"
+
+	"godir/internal/common/esx"
+	"godir/internal/common/redis"
+	"godir/internal/common/svc"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTrash 回收站列表（已软删除的素材）
+func (h *Material) ListTrash(c *gin.Context, req *types.MaterialTrashListReq) (*types.MaterialTrashListResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var materials []model.GodirMaterial
+	if err := h.DB.Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&materials).Error; err != nil {
+		return nil, fmt.Errorf("查询回收站失败: %w", err)
+	}
+
+	retention := redis.TrashRetention()
+	list := make([]types.MaterialTrashItem, 0, len(materials))
+	for _, m := range materials {
+		item := types.MaterialTrashItem{
+			ID:          m.ID,
+			FileName:    m.FileName,
+			FileSize:    m.FileSize,
+			ContentType: m.ContentType,
+			CreatedAt:   m.CreatedAt.Format("2006-01-02 15:04:05"),
+			DeletedAt:   m.DeletedAt.Time.Format("2006-01-02 15:04:05"),
+			PurgeAt:     m.DeletedAt.Time.Add(retention).Format("2006-01-02 15:04:05"),
+		}
+
+		if m.CoverOssFilePath != "" {
+			coverURL, err := svc.Minio().PresignedGetObject(h.Ctx, m.OssBucket, m.CoverOssFilePath, time.Hour,
+				map[string][]string{"response-content-disposition": {"inline"}})
+			if err == nil {
+				item.CoverPreviewURL = coverURL.String()
+			}
+		}
+
+		list = append(list, item)
+	}
+
+	return &types.MaterialTrashListResp{List: list}, nil
+}
+
+// Restore 从回收站恢复素材，并重新写入ES索引
+func (h *Material) Restore(c *gin.Context, req *types.MaterialRestoreReq) (*types.MaterialRestoreResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	materials, err := h.trashedMaterials(userID, req.Ids)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.DB.Unscoped().Model(&model.GodirMaterial{}).
+		Where("id IN ? AND user_id = ?", req.Ids, userID).
+		Update("deleted_at", nil).Error; err != nil {
+		return nil, fmt.Errorf("恢复素材失败: %w", err)
+	}
+
+	// 原文件夹已被删除的素材恢复到根目录
+	if err := h.DB.Model(&model.GodirMaterial{}).
+		Where("id IN ? AND user_id = ? AND folder_id IS NOT NULL", req.Ids, userID).
+		Where("folder_id NOT IN (?)", h.DB.Model(&model.GodirFolder{}).Select("id").Where("user_id = ?", userID)).
+		Update("folder_id", nil).Error; err != nil {
+		h.Log.Warnf("重置素材文件夹失败: %v", err)
+	}
+
+	for i := range materials {
+		if err := esx.IndexMaterial(h.Ctx, &materials[i]); err != nil {
+			h.Log.Warnf("ES索引失败, material_id=%d: %v", materials[i].ID, err)
+		}
+	}
+
+	return &types.MaterialRestoreResp{Message: fmt.Sprintf("已恢复%d个文件", len(materials))}, nil
+}
+
+// Purge 彻底删除回收站中的素材；All 为 true 时清空回收站
+func (h *Material) Purge(c *gin.Context, req *types.MaterialPurgeReq) (*types.MaterialPurgeResp, error) {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var materials []model.GodirMaterial
+	if req.All {
+		if err := h.DB.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Find(&materials).Error; err != nil {
+			return nil, fmt.Errorf("查询回收站失败: %w", err)
+		}
+	} else {
+		materials, err = h.trashedMaterials(userID, req.Ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := redis.PurgeMaterials(h.Ctx, h.DB, materials); err != nil {
+		return nil, fmt.Errorf("彻底删除失败: %w", err)
+	}
+
+	return &types.MaterialPurgeResp{Message: fmt.Sprintf("已彻底删除%d个文件", len(materials))}, nil
+}
+
+// trashedMaterials 查询当前用户回收站中的指定素材，有任一不存在时报错
+func (h *Material) trashedMaterials(userID uint, ids []uint) ([]model.GodirMaterial, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("请选择文件")
+	}
+
+	var materials []model.GodirMaterial
+	if err := h.DB.Unscoped().
+		Where("id IN ? AND user_id = ? AND deleted_at IS NOT NULL", ids, userID).
+		Find(&materials).Error; err != nil {
+		return nil, fmt.Errorf("查询回收站失败: %w", err)
+	}
+
+	found := make(map[uint]bool, len(materials))
+	for _, m := range materials {
+		found[m.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			return nil, fmt.Errorf("文件ID %d 不在回收站中", id)
+		}
+	}
+	return materials, nil
+}
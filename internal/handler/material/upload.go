@@ -0,0 +1,203 @@
+package material
+
+import (
+	"fmt"
+	"time"
+
+	"godir/internal/common/objref"
+	"godir/internal/common/redis"
+	"godir/internal/common/svc"
+	"godir/internal/common/util/mimeutil"
+
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// 上传凭证过期后仍允许调用 Save 的时间
+	uploadIntentGrace = 30 * time.Minute
+
+	// 保存锁的有效期，覆盖复制和校验所需的时间；请求异常退出时锁到期自动释放
+	uploadLockTTL = 10 * time.Minute
+)
+
+// uploadedObject 经服务端核实的已上传对象
+type uploadedObject struct {
+	Bucket      string
+	Key         string
+	Size        int64
+	ContentType string
+	Hash        string // SHA-256，超过同步计算上限时为空，由后台补算
+	CoverKey    string // 复用已有对象时沿用其封面
+	Shared      bool   // 复用的是其他素材的对象，而不是本次上传的
+	ReuseLater  bool   // 哈希由后台计算，算出后再按保存时的要求复用已有对象
+
+	intent *redis.UploadIntent // 本次上传的意图，登记成功后由 finishUpload 消费
+}
+
+// lockUpload 对同一上传加保存锁，返回释放函数；已有请求在保存时直接拒绝
+func (h *Material) lockUpload(key string) (func(), error) {
+	ok, err := redis.LockUpload(h.Ctx, key, uploadLockTTL)
+	if err != nil {
+		h.Log.Errorf("获取保存锁失败: %v", err)
+		return nil, fmt.Errorf("保存文件信息失败")
+	}
+	if !ok {
+		return nil, fmt.Errorf("该文件正在保存，请稍后重试")
+	}
+	return func() {
+		if err := redis.UnlockUpload(h.Ctx, key); err != nil {
+			h.Log.Warnf("释放保存锁失败, key=%s: %v", key, err)
+		}
+	}, nil
+}
+
+// verifyUpload 核对上传意图和暂存对象，复制到正式key后校验实际大小和嗅探出的内容类型。
+// 临时凭证过期前客户端仍能写入暂存key，因此校验和登记都针对客户端无权写入的正式副本；
+// 分片上传（uploadID 非空）不复制：客户端只持有绑定 uploadId 的分片预签名地址，合并后即失效，无法再改写对象，
+// 合并结果原地登记，避免在请求内同步复制可达数TB的对象；
+// 调用方须持有保存锁（见 lockUpload），上传意图在登记成功后才消费：
+// 复制、读取等基础设施错误保留暂存对象和意图，客户端可以直接重试，只有校验不通过时才删除上传的文件。
+// uploadID 为空表示普通上传
+func (h *Material) verifyUpload(userID uint, bucket, key, uploadID string, fileSize int64, contentType string) (*uploadedObject, error) {
+	intent, err := redis.GetUploadIntent(h.Ctx, key)
+	if err != nil {
+		h.Log.Errorf("查询上传意图失败: %v", err)
+		return nil, fmt.Errorf("保存文件信息失败")
+	}
+	if intent == nil || intent.UserID != userID || intent.Bucket != bucket || intent.UploadID != uploadID {
+		return nil, fmt.Errorf("上传凭证无效或已过期，请重新上传")
+	}
+
+	minioClient := svc.Minio()
+	staged, err := minioClient.StatObject(h.Ctx, intent.Bucket, intent.Key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, fmt.Errorf("文件尚未上传完成")
+		}
+		h.Log.Errorf("查询对象信息失败: %v", err)
+		return nil, fmt.Errorf("保存文件信息失败，请重试")
+	}
+	if staged.Size != intent.FileSize || staged.Size != fileSize {
+		h.rejectUpload(intent, "")
+		return nil, fmt.Errorf("文件大小与申请上传时不一致")
+	}
+
+	declared := contentType
+	if declared == "" {
+		declared = intent.ContentType
+	}
+
+	objectKey, stat := intent.Key, staged
+	if uploadID == "" {
+		if objectKey, stat, err = h.copyUpload(intent, staged, declared); err != nil {
+			return nil, err
+		}
+		if stat.Size != intent.FileSize {
+			h.rejectUpload(intent, objectKey)
+			return nil, fmt.Errorf("文件大小与申请上传时不一致")
+		}
+	}
+
+	// 读取文件头嗅探真实类型
+	opts := minio.GetObjectOptions{}
+	if stat.Size > mimeutil.SniffLen {
+		_ = opts.SetRange(0, mimeutil.SniffLen-1)
+	}
+	head, err := minioClient.GetObject(h.Ctx, intent.Bucket, objectKey, opts)
+	if err != nil {
+		h.Log.Errorf("读取对象失败: %v", err)
+		return nil, fmt.Errorf("保存文件信息失败，请重试")
+	}
+	defer head.Close()
+
+	detected, err := mimeutil.Sniff(head)
+	if err != nil {
+		h.Log.Errorf("识别文件类型失败: %v", err)
+		return nil, fmt.Errorf("保存文件信息失败，请重试")
+	}
+
+	if !mimeutil.Compatible(declared, detected) {
+		h.Log.Warnf("文件类型不一致, user_id=%d key=%s declared=%s detected=%s", userID, objectKey, declared, detected)
+		h.rejectUpload(intent, rejectKey(intent, objectKey))
+		return nil, fmt.Errorf("文件内容与声明的类型不一致")
+	}
+
+	// 小文件同步计算哈希，以便保存时就能提示重复
+	var hash string
+	if stat.Size <= objref.HashSyncLimit() {
+		if hash, err = objref.Hash(h.Ctx, intent.Bucket, objectKey); err != nil {
+			h.Log.Warnf("计算内容哈希失败, key=%s: %v", objectKey, err)
+		}
+	}
+
+	return &uploadedObject{
+		Bucket:      intent.Bucket,
+		Key:         objectKey,
+		Size:        stat.Size,
+		ContentType: detected,
+		Hash:        hash,
+		intent:      intent,
+	}, nil
+}
+
+// copyUpload 服务端复制到正式key并返回副本信息；ETag 不一致说明暂存对象在核对后被替换，复制失败，重试时重新核对
+func (h *Material) copyUpload(intent *redis.UploadIntent, staged minio.ObjectInfo, contentType string) (string, minio.ObjectInfo, error) {
+	minioClient := svc.Minio()
+	objectKey := objectKeyFor(intent.Key)
+
+	dst := minio.CopyDestOptions{Bucket: intent.Bucket, Object: objectKey, ReplaceMetadata: true}
+	if contentType != "" {
+		dst.UserMetadata = map[string]string{"Content-Type": contentType}
+	}
+	src := minio.CopySrcOptions{Bucket: intent.Bucket, Object: intent.Key, MatchETag: staged.ETag}
+	if _, err := minioClient.ComposeObject(h.Ctx, dst, src); err != nil {
+		h.Log.Errorf("复制上传对象失败, key=%s: %v", intent.Key, err)
+		return "", minio.ObjectInfo{}, fmt.Errorf("保存文件信息失败，请重试")
+	}
+
+	stat, err := minioClient.StatObject(h.Ctx, intent.Bucket, objectKey, minio.StatObjectOptions{})
+	if err != nil {
+		h.Log.Errorf("查询对象信息失败: %v", err)
+		return "", minio.ObjectInfo{}, fmt.Errorf("保存文件信息失败，请重试")
+	}
+	return objectKey, stat, nil
+}
+
+// finishUpload 登记成功后消费上传意图并删除暂存对象，删除失败的由孤儿对象回收兜底；
+// 分片上传的对象原地登记，不删除
+func (h *Material) finishUpload(intent *redis.UploadIntent) {
+	if _, err := redis.ConsumeUploadIntent(h.Ctx, intent.Key); err != nil {
+		h.Log.Warnf("消费上传意图失败: %v", err)
+	}
+	if intent.UploadID == "" {
+		h.removeStaged(intent)
+	}
+}
+
+// rejectKey 校验不通过时需额外删除的正式对象，分片上传原地登记，与暂存对象是同一个
+func rejectKey(intent *redis.UploadIntent, objectKey string) string {
+	if objectKey == intent.Key {
+		return ""
+	}
+	return objectKey
+}
+
+// rejectUpload 校验不通过时作废上传意图，删除暂存对象和已复制的正式对象（objectKey 为空表示尚未复制）
+func (h *Material) rejectUpload(intent *redis.UploadIntent, objectKey string) {
+	if _, err := redis.ConsumeUploadIntent(h.Ctx, intent.Key); err != nil {
+		h.Log.Warnf("作废上传意图失败: %v", err)
+	}
+	h.removeStaged(intent)
+	if objectKey != "" {
+		if err := svc.Minio().RemoveObject(h.Ctx, intent.Bucket, objectKey, minio.RemoveObjectOptions{}); err != nil {
+			h.Log.Warnf("删除未通过校验的对象失败: %v", err)
+		}
+	}
+}
+
+// removeStaged 删除暂存对象，失败的由孤儿对象回收兜底
+func (h *Material) removeStaged(intent *redis.UploadIntent) {
+	if err := svc.Minio().RemoveObject(h.Ctx, intent.Bucket, intent.Key, minio.RemoveObjectOptions{}); err != nil {
+		h.Log.Warnf("删除暂存对象失败, key=%s: %v", intent.Key, err)
+	}
+}
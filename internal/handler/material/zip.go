@@ -0,0 +1,223 @@
+package material
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"godir/internal/common/quota"
+	"godir/internal/common/svc"
+	"godir/internal/common/util/mimeutil"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+)
+
+const defaultZipMaxSize = 2 << 30
+
+// zipEntry 压缩包中的一个文件
+type zipEntry struct {
+	material *model.GodirMaterial
+	name     string // 包内路径
+}
+
+// DownloadZip 将选中的素材或整个文件夹打包为ZIP，边从MinIO读取边写给客户端，不落盘
+func (h *Material) DownloadZip(c *gin.Context, req *types.MaterialZipReq) error {
+	userID, err := currentUserID(c)
+	if err != nil {
+		return err
+	}
+
+	var (
+		entries     []zipEntry
+		archiveName string
+	)
+	if req.FolderID != 0 {
+		entries, archiveName, err = h.folderZipEntries(userID, req.FolderID)
+	} else {
+		entries, err = h.materialZipEntries(userID, req.Ids)
+		archiveName = fmt.Sprintf("materials-%s", time.Now().Format("20060102150405"))
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("没有可下载的文件")
+	}
+
+	var total int64
+	for _, e := range entries {
+		total += e.material.FileSize
+	}
+	if limit := zipMaxSize(); total > limit {
+		return fmt.Errorf("所选文件共 %s，超过打包下载上限 %s", quota.FormatBytes(total), quota.FormatBytes(limit))
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"; filename*=UTF-8''%s.zip",
+		asciiFileName(archiveName), url.PathEscape(archiveName)))
+	c.Status(http.StatusOK)
+
+	// 响应头已发出，之后出错只能中断，客户端会得到不完整的压缩包
+	zw := zip.NewWriter(c.Writer)
+	for _, e := range entries {
+		if err := h.writeZipEntry(c, zw, &e); err != nil {
+			h.Log.Errorf("打包下载中断, material_id=%d: %v", e.material.ID, err)
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		h.Log.Errorf("打包下载中断: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (h *Material) writeZipEntry(c *gin.Context, zw *zip.Writer, e *zipEntry) error {
+	obj, err := svc.Minio().GetObject(c.Request.Context(), e.material.OssBucket, e.material.OssFilePath, minio.GetObjectOptions{})
+	if err != nil {
+		return err
+	}
+	defer obj.Close()
+
+	// 图片音视频本身已压缩，直接存储以节省CPU
+	method := zip.Deflate
+	switch mimeutil.Family(e.material.ContentType) {
+	case mimeutil.FamilyImage, mimeutil.FamilyVideo, mimeutil.FamilyAudio:
+		method = zip.Store
+	}
+
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     e.name,
+		Method:   method,
+		Modified: e.material.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, obj)
+	return err
+}
+
+// materialZipEntries 按ID选择的素材，须全部属于当前用户
+func (h *Material) materialZipEntries(userID uint, ids []uint) ([]zipEntry, error) {
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("请选择要下载的文件")
+	}
+
+	var materials []model.GodirMaterial
+	if err := h.DB.Where("id IN (?) AND user_id = ?", ids, userID).Order("id").Find(&materials).Error; err != nil {
+		return nil, fmt.Errorf("查询文件失败: %w", err)
+	}
+
+	foundIds := make(map[uint]bool)
+	for _, material := range materials {
+		foundIds[material.ID] = true
+	}
+	for _, id := range ids {
+		if !foundIds[id] {
+			return nil, fmt.Errorf("文件ID %d 不存在或无权限下载", id)
+		}
+	}
+
+	names := newZipNames()
+	entries := make([]zipEntry, 0, len(materials))
+	for i := range materials {
+		entries = append(entries, zipEntry{material: &materials[i], name: names.add("", materials[i].FileName)})
+	}
+	return entries, nil
+}
+
+// folderZipEntries 文件夹及其子文件夹中的全部素材，包内保持目录结构
+func (h *Material) folderZipEntries(userID, folderID uint) ([]zipEntry, string, error) {
+	root, err := h.getFolder(userID, folderID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	folderIDs, err := subtreeIDs(h.DB, userID, root.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	var folders []model.GodirFolder
+	if err := h.DB.Where("user_id = ? AND id IN ?", userID, folderIDs).Find(&folders).Error; err != nil {
+		return nil, "", fmt.Errorf("查询文件夹失败: %w", err)
+	}
+	byID := make(map[uint]*model.GodirFolder, len(folders))
+	for i := range folders {
+		byID[folders[i].ID] = &folders[i]
+	}
+
+	// 子文件夹相对于打包文件夹的路径，同级重名的文件夹同样加序号区分
+	names := newZipNames()
+	dirs := map[uint]string{root.ID: ""}
+	var dirOf func(id uint) string
+	dirOf = func(id uint) string {
+		if dir, ok := dirs[id]; ok {
+			return dir
+		}
+		f := byID[id]
+		dir := names.add(dirOf(*f.ParentID), f.Name)
+		dirs[id] = dir
+		return dir
+	}
+
+	var materials []model.GodirMaterial
+	if err := h.DB.Where("user_id = ? AND folder_id IN ?", userID, folderIDs).Order("folder_id, id").Find(&materials).Error; err != nil {
+		return nil, "", fmt.Errorf("查询文件失败: %w", err)
+	}
+
+	entries := make([]zipEntry, 0, len(materials))
+	for i := range materials {
+		dir := dirOf(*materials[i].FolderID)
+		entries = append(entries, zipEntry{material: &materials[i], name: names.add(dir, materials[i].FileName)})
+	}
+	return entries, root.Name, nil
+}
+
+// zipNames 分配包内不重复的路径，同一目录下重名时追加 " (n)"
+type zipNames map[string]bool
+
+func newZipNames() zipNames {
+	return make(zipNames)
+}
+
+func (n zipNames) add(dir, name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		name = "untitled"
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := path.Join(dir, name)
+	for i := 1; n[strings.ToLower(candidate)]; i++ {
+		candidate = path.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+	n[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func zipMaxSize() int64 {
+	if limit := svc.Cfg().Storage.ZipMaxSize; limit > 0 {
+		return limit
+	}
+	return defaultZipMaxSize
+}
+
+// asciiFileName 供不支持 filename* 的客户端使用的文件名，非ASCII字符替换为下划线
+func asciiFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -12,6 +12,11 @@ func RegisterRouter(r *gin.Engine) {
 	RegisterMaterialRouter(r)
 	RegisterVolcEngineRouter(r)
 	RegisterAiRouter(r)
+	RegisterAdminRouter(r)
 
-	redis.StartThumbnailWorker()
+	redis.StartCleanupWorker()
+	redis.StartHashWorker()
+	redis.StartOrphanGC()
+	redis.StartTrashPurger()
+	redis.StartStorageReconciler()
 }
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"godir/internal/common/ginx"
+	"godir/internal/handler/apikey"
+	"godir/internal/handler/auth"
 	"godir/internal/handler/user"
 
 	"github.com/gin-gonic/gin"
@@ -13,10 +15,18 @@ func RegisterUserRouter(r *gin.Engine) {
 
 	// 需要认证的用户路由组
 	protected := r.Group("/user")
-	protected.Use(ginx.AuthMiddleware())
+	protected.Use(ginx.AuthMiddleware(), ginx.RejectAPIKey())
 	{
 		protected.GET("/profile", ginx.WrapHandlerObj((*user.User).Profile))
 		protected.PUT("/profile", ginx.WrapHandlerObj((*user.User).UpdateProfile))
 		protected.POST("/avatar", ginx.WrapHandlerObj((*user.User).UploadAvatar))
+		protected.GET("/storage", ginx.WrapHandlerObj((*user.User).Storage))
+
+		protected.GET("/api-keys", ginx.WrapHandlerObj((*apikey.APIKey).List))
+		protected.POST("/api-keys/create", ginx.WrapHandlerObj((*apikey.APIKey).Create))
+		protected.POST("/api-keys/revoke", ginx.WrapHandlerObj((*apikey.APIKey).Revoke))
+
+		protected.GET("/sessions", ginx.WrapHandlerObj((*auth.Auth).ListSessions))
+		protected.POST("/sessions/revoke", ginx.WrapHandlerObj((*auth.Auth).RevokeSession))
 	}
 }
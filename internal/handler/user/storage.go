@@ -0,0 +1,85 @@
+package user
+
+import (
+	"fmt"
+	"sort"
+
+	"godir/internal/common/quota"
+	"godir/internal/common/util/mimeutil"
+	"godir/internal/model"
+	"godir/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Storage 当前用户的存储用量，按内容类型分类汇总
+func (h *User) Storage(c *gin.Context, req *types.UserStorageReq) (*types.UserStorageResp, error) {
+	userID, exists := c.Get("userId")
+	if !exists {
+		return nil, fmt.Errorf("未登录")
+	}
+
+	userIDUint, ok := userID.(uint)
+	if !ok {
+		return nil, fmt.Errorf("用户ID格式错误")
+	}
+
+	user, err := h.GetGodirUserByID(userIDUint)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+
+	// 按内容类型分组统计，回收站中的文件单独列出
+	var rows []struct {
+		ContentType string
+		Trashed     bool
+		Count       int64
+		Bytes       int64
+	}
+	if err := h.DB.Unscoped().Model(&model.GodirMaterial{}).
+		Select("content_type, deleted_at IS NOT NULL AS trashed, COUNT(*) AS count, COALESCE(SUM(file_size), 0) AS bytes").
+		Where("user_id = ?", userIDUint).
+		Group("content_type, trashed").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("统计存储用量失败: %w", err)
+	}
+
+	families := map[string]*types.StorageBreakdown{}
+	var trash types.StorageBreakdown
+	for _, r := range rows {
+		if r.Trashed {
+			trash.Count += r.Count
+			trash.Bytes += r.Bytes
+			continue
+		}
+		family := mimeutil.Family(r.ContentType)
+		b, ok := families[family]
+		if !ok {
+			b = &types.StorageBreakdown{Type: family}
+			families[family] = b
+		}
+		b.Count += r.Count
+		b.Bytes += r.Bytes
+	}
+
+	breakdown := make([]types.StorageBreakdown, 0, len(families))
+	for _, b := range families {
+		breakdown = append(breakdown, *b)
+	}
+	sort.Slice(breakdown, func(i, j int) bool { return breakdown[i].Bytes > breakdown[j].Bytes })
+
+	resp := &types.UserStorageResp{
+		Used:      user.StorageUsed,
+		Quota:     quota.Effective(user),
+		Available: -1,
+		Breakdown: breakdown,
+		Trash:     trash,
+	}
+	if resp.Quota > 0 {
+		resp.Available = resp.Quota - resp.Used
+		if resp.Available < 0 {
+			resp.Available = 0
+		}
+	}
+	return resp, nil
+}
@@ -75,15 +75,17 @@ func (h *User) Profile(c *gin.Context, req *types.UserProfileReq) (*types.UserPr
 	}
 
 	return &types.UserProfileResp{
-		ID:       user.ID,
-		Username: user.Username,
-		Avatar:   finalAvatarURL,
-		Nickname: user.Nickname,
-		Gender:   user.Gender,
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.GetEmail(),
+		EmailVerified: user.EmailVerified,
+		Avatar:        finalAvatarURL,
+		Nickname:      user.Nickname,
+		Gender:        user.Gender,
 	}, nil
 }
 
-// UpdateProfile 更新当前用户个人信息
+// UpdateProfile 更新当前用户个人信息；邮箱需经确认才能修改，见 Auth.ChangeEmail
 func (h *User) UpdateProfile(c *gin.Context, req *types.UserProfileUpdateReq) (*types.UserProfileResp, error) {
 	// 从上下文获取用户信息
 	userInfo, exists := c.Get("userInfo")
@@ -115,11 +117,13 @@ func (h *User) UpdateProfile(c *gin.Context, req *types.UserProfileUpdateReq) (*
 	}
 
 	return &types.UserProfileResp{
-		ID:       user.ID,
-		Username: user.Username,
-		Avatar:   finalAvatarURL,
-		Nickname: user.Nickname,
-		Gender:   user.Gender,
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.GetEmail(),
+		EmailVerified: user.EmailVerified,
+		Avatar:        finalAvatarURL,
+		Nickname:      user.Nickname,
+		Gender:        user.Gender,
 	}, nil
 }
 
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"godir/internal/common/apikey"
 	"godir/internal/common/ginx"
 	"godir/internal/handler/volcengine"
+	"godir/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
@@ -11,13 +13,18 @@ func RegisterVolcEngineRouter(r *gin.Engine) {
 	protected := r.Group("/volcengine")
 	protected.Use(ginx.AuthMiddleware())
 	{
-		protected.POST("/knowledge-base/create", ginx.WrapHandlerObj((*volcengine.VolcEngine).CreateKnowledgeBase))
-		protected.GET("/knowledge-base/list", ginx.WrapHandlerObj((*volcengine.VolcEngine).ListKnowledgeBase))
-		protected.POST("/knowledge-base/delete", ginx.WrapHandlerObj((*volcengine.VolcEngine).DeleteKnowledgeBase))
-		protected.POST("/document/upload", ginx.WrapHandlerObj((*volcengine.VolcEngine).UploadDocument))
-		protected.GET("/document/list", ginx.WrapHandlerObj((*volcengine.VolcEngine).ListDocument))
-		protected.POST("/document/delete", ginx.WrapHandlerObj((*volcengine.VolcEngine).DeleteDocument))
-		protected.POST("/chat", ginx.WrapHandlerObj((*volcengine.VolcEngine).Chat))
-		protected.POST("/search", ginx.WrapHandlerObj((*volcengine.VolcEngine).Search))
+		// 管理知识库和文档需要编辑权限且不接受API Key；对话与检索需要 kb:chat 权限范围
+		managed := protected.Group("", ginx.RejectAPIKey())
+		writable := managed.Group("", ginx.RequireRole(model.RoleAdmin, model.RoleEditor))
+		chat := protected.Group("", ginx.RequireScope(apikey.ScopeKBChat))
+
+		writable.POST("/knowledge-base/create", ginx.WrapHandlerObj((*volcengine.VolcEngine).CreateKnowledgeBase))
+		managed.GET("/knowledge-base/list", ginx.WrapHandlerObj((*volcengine.VolcEngine).ListKnowledgeBase))
+		writable.POST("/knowledge-base/delete", ginx.WrapHandlerObj((*volcengine.VolcEngine).DeleteKnowledgeBase))
+		writable.POST("/document/upload", ginx.WrapHandlerObj((*volcengine.VolcEngine).UploadDocument))
+		managed.GET("/document/list", ginx.WrapHandlerObj((*volcengine.VolcEngine).ListDocument))
+		writable.POST("/document/delete", ginx.WrapHandlerObj((*volcengine.VolcEngine).DeleteDocument))
+		chat.POST("/chat", ginx.WrapHandlerObj((*volcengine.VolcEngine).Chat))
+		chat.POST("/search", ginx.WrapHandlerObj((*volcengine.VolcEngine).Search))
 	}
 }
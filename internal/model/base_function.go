@@ -2,6 +2,7 @@ package model
 
 import (
 	"errors"
+	"fmt"
 
 	"gorm.io/gorm"
 )
@@ -93,3 +94,55 @@ func (m *Model[T]) FindPage(dest *[]T, page, pageSize int, conds ...any) (int64,
 	err := query.Offset((page - 1) * pageSize).Limit(pageSize).Find(dest).Error
 	return total, err
 }
+
+// CursorPage 游标（keyset）分页参数：按 (SortColumn, id) 排序，从上一页最后一行之后开始取
+type CursorPage struct {
+	SortColumn string // 排序列，调用方需保证来自白名单
+	Desc       bool
+	AfterValue any  // 上一页最后一行的排序列值，AfterID 为0表示第一页
+	AfterID    uint // 上一页最后一行的ID，排序列相同时用于确定先后
+	Limit      int
+}
+
+// FindCursorPage 游标分页查询，返回满足条件的总数以及是否还有下一页。
+// scopes 为过滤条件，同时作用于总数统计；排序列相同时按 id 同方向排序，保证翻页稳定
+func (m *Model[T]) FindCursorPage(dest *[]T, page CursorPage, scopes ...func(*gorm.DB) *gorm.DB) (total int64, hasMore bool, err error) {
+	if page.Limit <= 0 {
+		page.Limit = 10
+	}
+
+	query := m.db.Model(new(T)).Scopes(scopes...)
+	if err := query.Count(&total).Error; err != nil {
+		return 0, false, err
+	}
+	if total == 0 {
+		*dest = []T{}
+		return 0, false, nil
+	}
+
+	op, dir := ">", "ASC"
+	if page.Desc {
+		op, dir = "<", "DESC"
+	}
+
+	query = m.db.Model(new(T)).Scopes(scopes...)
+	if page.AfterID > 0 {
+		query = query.Where(
+			fmt.Sprintf("(%[1]s %[2]s ?) OR (%[1]s = ? AND id %[2]s ?)", page.SortColumn, op),
+			page.AfterValue, page.AfterValue, page.AfterID,
+		)
+	}
+
+	// 多取一条用于判断是否还有下一页
+	err = query.Order(fmt.Sprintf("%s %s, id %s", page.SortColumn, dir, dir)).
+		Limit(page.Limit + 1).
+		Find(dest).Error
+	if err != nil {
+		return 0, false, err
+	}
+	if len(*dest) > page.Limit {
+		*dest = (*dest)[:page.Limit]
+		hasMore = true
+	}
+	return total, hasMore, nil
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GodirAPIKey 用户的个人访问令牌，仅保存哈希；软删除即吊销
+type GodirAPIKey struct {
+	gorm.Model
+
+	UserID     uint       `gorm:"not null;index"`
+	Name       string     `gorm:"size:128;not null"`
+	Prefix     string     `gorm:"size:16;not null"` // 明文前几位，用于列表中辨认
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex"`
+	Scopes     string     `gorm:"size:255;not null"` // 逗号分隔的权限范围
+	ExpiresAt  *time.Time // 为空表示永不过期
+	LastUsedAt *time.Time
+}
+
+func (GodirAPIKey) TableName() string {
+	return "godir_api_key"
+}
+
+// ScopeList 返回权限范围列表
+func (k *GodirAPIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
@@ -0,0 +1,24 @@
+package model
+
+import "gorm.io/gorm"
+
+// 审计事件类型
+const (
+	AuditActionAccountLockout = "account_lockout" // 连续登录失败导致账号锁定
+	AuditActionIPLockout      = "ip_lockout"      // 同一IP登录失败过多导致锁定
+)
+
+// GodirAuditLog 安全审计记录
+type GodirAuditLog struct {
+	gorm.Model
+
+	UserID   uint   `gorm:"index"` // 用户不存在时为0
+	Username string `gorm:"size:128;index"`
+	Action   string `gorm:"size:64;not null;index"`
+	IP       string `gorm:"size:64"`
+	Detail   string `gorm:"type:text"`
+}
+
+func (GodirAuditLog) TableName() string {
+	return "godir_audit_log"
+}
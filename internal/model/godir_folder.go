@@ -0,0 +1,18 @@
+package model
+
+import "gorm.io/gorm"
+
+// GodirFolder 素材文件夹，ParentID 为空表示位于根目录
+type GodirFolder struct {
+	gorm.Model
+
+	UserID   uint   `gorm:"not null;index"`
+	ParentID *uint  `gorm:"index"`
+	Name     string `gorm:"size:255;not null"`
+
+	Control
+}
+
+func (GodirFolder) TableName() string {
+	return "godir_folder"
+}
@@ -13,9 +13,13 @@ type GodirMaterial struct {
 	ContentType      string `gorm:"size:100"`
 	OssBucket        string `gorm:"size:100;not null"`
 	OssFilePath      string `gorm:"size:500;not null"`
-	CoverOssFilePath string `gorm:"size:500"` // Cover 为封面/缩略图信息
+	CoverOssFilePath string `gorm:"size:500"`      // Cover 为封面/缩略图信息
+	FolderID         *uint  `gorm:"index"`         // 所在文件夹，为空表示根目录
+	ContentHash      string `gorm:"size:64;index"` // 文件内容的SHA-256（十六进制），大文件由后台计算，计算完成前为空
 	Control
 
+	Tags []GodirTag `gorm:"many2many:godir_material_tag;joinForeignKey:MaterialID;joinReferences:TagID"`
+
 	// CoverURL string `gorm:"size:1000"`
 	// URL      string `gorm:"size:1000"`
 }
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// GodirObject 存储对象的引用计数；内容相同的素材可共用同一个对象，计数归零后才删除对象
+type GodirObject struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	Bucket      string `gorm:"size:100;not null;uniqueIndex:idx_bucket_key"`
+	ObjectKey   string `gorm:"size:500;not null;uniqueIndex:idx_bucket_key"`
+	ContentHash string `gorm:"size:64;index"` // SHA-256（十六进制）
+	Size        int64  `gorm:"not null"`
+	RefCount    int    `gorm:"not null;default:0"` // 引用该对象的素材数（含回收站中的）
+}
+
+func (GodirObject) TableName() string {
+	return "godir_object"
+}
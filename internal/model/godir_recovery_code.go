@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GodirRecoveryCode 两步验证恢复码，仅保存哈希，每个只能使用一次
+type GodirRecoveryCode struct {
+	gorm.Model
+
+	UserID   uint   `gorm:"not null;index"`
+	CodeHash string `gorm:"size:64;not null"`
+	UsedAt   *time.Time
+}
+
+func (GodirRecoveryCode) TableName() string {
+	return "godir_recovery_code"
+}
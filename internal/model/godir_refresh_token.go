@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GodirRefreshToken 刷新token，仅保存哈希；同一次登录轮换出的token属于同一家族
+type GodirRefreshToken struct {
+	gorm.Model
+
+	UserID    uint       `gorm:"not null;index"`
+	FamilyID  string     `gorm:"size:64;not null;index"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // 已轮换的时间，再次出现即视为重放
+	RevokedAt *time.Time // 家族被吊销的时间
+}
+
+func (GodirRefreshToken) TableName() string {
+	return "godir_refresh_token"
+}
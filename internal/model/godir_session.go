@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GodirSession 登录会话，每次登录生成一条；SessionID 与刷新token家族ID相同，刷新时沿用
+type GodirSession struct {
+	gorm.Model
+
+	SessionID  string    `gorm:"size:64;not null;uniqueIndex"`
+	UserID     uint      `gorm:"not null;index"`
+	TokenID    string    `gorm:"size:64"` // 最近一次签发的访问token jti
+	UserAgent  string    `gorm:"size:512"`
+	IP         string    `gorm:"size:64"`
+	LastSeenAt time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+}
+
+func (GodirSession) TableName() string {
+	return "godir_session"
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GodirShareLink 私有分享链接，指向单个素材或一个文件夹；撤销即软删除
+type GodirShareLink struct {
+	gorm.Model
+
+	UserID        uint       `gorm:"not null;index"`
+	Slug          string     `gorm:"size:32;not null;uniqueIndex"`
+	MaterialID    *uint      `gorm:"index"` // MaterialID 和 FolderID 有且只有一个不为空
+	FolderID      *uint      `gorm:"index"`
+	PasswordHash  string     `gorm:"size:100"` // 为空表示无需密码
+	ExpiresAt     *time.Time // 为空表示永不过期
+	MaxDownloads  int        `gorm:"not null"` // 0表示不限制
+	DownloadCount int        `gorm:"not null"` // 签发下载地址的次数，同一地址在有效期内可重复使用
+	ViewCount     int        `gorm:"not null"` // 签发在线预览地址的次数，不受 MaxDownloads 限制
+	AllowPreview  bool       `gorm:"not null"`
+	AllowDownload bool       `gorm:"not null"`
+
+	Control
+}
+
+func (GodirShareLink) TableName() string {
+	return "godir_share_link"
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GodirTag 用户自定义的素材标签，同一用户下名称唯一
+type GodirTag struct {
+	gorm.Model
+
+	UserID uint   `gorm:"not null;uniqueIndex:idx_user_tag_name"`
+	Name   string `gorm:"size:50;not null;uniqueIndex:idx_user_tag_name"`
+	Color  string `gorm:"size:20"` // 前端展示用的颜色，如 #1677ff
+
+	Control
+}
+
+func (GodirTag) TableName() string {
+	return "godir_tag"
+}
+
+// GodirMaterialTag 素材与标签的多对多关联
+type GodirMaterialTag struct {
+	MaterialID uint `gorm:"primaryKey"`
+	TagID      uint `gorm:"primaryKey;index"`
+	CreatedAt  time.Time
+}
+
+func (GodirMaterialTag) TableName() string {
+	return "godir_material_tag"
+}
@@ -2,15 +2,45 @@ package model
 
 import "gorm.io/gorm"
 
+// 用户角色
+const (
+	RoleAdmin    = "admin"    // 管理员：可管理AI应用、审核发布内容、调整用户角色
+	RoleEditor   = "editor"   // 编辑：可上传、修改、删除、发布自己的素材
+	RoleViewer   = "viewer"   // 浏览者：可查看素材并参与点赞等互动
+	RoleReadOnly = "readonly" // 只读：仅可查看
+)
+
+// IsValidRole 判断是否为支持的角色
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleEditor, RoleViewer, RoleReadOnly:
+		return true
+	}
+	return false
+}
+
 type GodirUser struct {
 	gorm.Model
 
 	Username string `gorm:"size:128;not null"`
-	Password string `gorm:"size:255;not null"` // 存储加密后的密码
-	
+	Password string `gorm:"size:255;not null"` // 存储加密后的密码；仅通过OIDC登录的用户为空
+	Role     string `gorm:"size:32;not null;default:editor"`
+
+	// 邮箱唯一，未设置时为NULL（唯一索引允许多个NULL）；读写经 GetEmail、NullEmail 转换
+	Email         *string `gorm:"size:255;uniqueIndex:uk_godir_user_email"`
+	EmailVerified bool    `gorm:"default:false"`
+
+	// 两步验证：TOTPSecret 在注册验证器后写入，确认验证码后 TOTPEnabled 才置为 true
+	TOTPSecret  string `gorm:"size:64"`
+	TOTPEnabled bool   `gorm:"default:false"`
+
+	// 存储配额：StorageQuota 为空时使用配置中的默认值，0表示不限制；StorageUsed 含回收站中的文件
+	StorageQuota *int64
+	StorageUsed  int64 `gorm:"not null;default:0"`
+
 	// 新增用户信息字段
-	Avatar  string `gorm:"size:255"` // 头像URL
-	Nickname string `gorm:"size:128"` // 昵称
+	Avatar   string `gorm:"size:255"`  // 头像URL
+	Nickname string `gorm:"size:128"`  // 昵称
 	Gender   int    `gorm:"default:0"` // 性别: 0-未知, 1-男, 2-女
 
 	Control
@@ -18,4 +48,20 @@ type GodirUser struct {
 
 func (GodirUser) TableName() string {
 	return "godir_user"
-}
\ No newline at end of file
+}
+
+// GetEmail 返回邮箱，未设置时为空字符串
+func (u *GodirUser) GetEmail() string {
+	if u.Email == nil {
+		return ""
+	}
+	return *u.Email
+}
+
+// NullEmail 将空邮箱转换为NULL，用于写入 GodirUser.Email
+func NullEmail(email string) *string {
+	if email == "" {
+		return nil
+	}
+	return &email
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// GodirUserIdentity 外部身份提供方（OIDC）账号与本地用户的关联，一个用户可关联多个身份
+type GodirUserIdentity struct {
+	gorm.Model
+
+	UserID   uint   `gorm:"not null;index"`
+	Provider string `gorm:"size:64;not null;uniqueIndex:idx_provider_subject"`  // 配置中的提供方名称
+	Subject  string `gorm:"size:255;not null;uniqueIndex:idx_provider_subject"` // ID Token 中的 sub
+	Email    string `gorm:"size:255"`                                           // 关联时提供方返回的邮箱，仅作展示
+}
+
+func (GodirUserIdentity) TableName() string {
+	return "godir_user_identity"
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 一次性token用途
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+	TokenPurposeChangeEmail   = "change_email" // Email 为待确认的新邮箱
+)
+
+// GodirUserToken 邮件中下发的一次性token（邮箱验证、重置密码、修改邮箱），仅保存哈希
+type GodirUserToken struct {
+	gorm.Model
+
+	UserID    uint      `gorm:"not null;index"`
+	Purpose   string    `gorm:"size:32;not null"`
+	Email     string    `gorm:"size:255"` // 下发时的邮箱，邮箱变更后旧token失效
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+func (GodirUserToken) TableName() string {
+	return "godir_user_token"
+}
@@ -0,0 +1,103 @@
+package types
+
+// AI应用管理接口
+type (
+	AdminAiAppCreateReq struct {
+		Name  string `json:"name" binding:"required"`
+		AppID string `json:"appId" binding:"required"`
+		Desc  string `json:"desc"`
+		Icon  string `json:"icon"`
+		Cover string `json:"cover"`
+	}
+
+	AdminAiAppUpdateReq struct {
+		ID    uint   `json:"id" binding:"required"`
+		Name  string `json:"name"`
+		AppID string `json:"appId"`
+		Desc  string `json:"desc"`
+		Icon  string `json:"icon"`
+		Cover string `json:"cover"`
+	}
+
+	AdminAiAppDeleteReq struct {
+		Ids []uint `json:"ids" binding:"required"`
+	}
+
+	AdminAiAppResp struct {
+		App AiAppInfo `json:"app"`
+	}
+
+	AdminDeleteResp struct {
+		Deleted int64 `json:"deleted"`
+	}
+)
+
+// 发布内容审核接口
+type (
+	AdminPublishedDeleteReq struct {
+		Ids []uint `json:"ids" binding:"required"`
+	}
+)
+
+// 用户角色管理接口
+type (
+	AdminSetUserRoleReq struct {
+		UserID uint   `json:"userId" binding:"required"`
+		Role   string `json:"role" binding:"required"`
+	}
+
+	AdminSetUserRoleResp struct {
+		UserID uint   `json:"userId"`
+		Role   string `json:"role"`
+	}
+)
+
+// 用户存储配额管理接口
+type (
+	// AdminSetUserQuotaReq Quota 为空表示恢复默认配额，0 表示不限制
+	AdminSetUserQuotaReq struct {
+		UserID uint   `json:"userId" binding:"required"`
+		Quota  *int64 `json:"quota" binding:"omitempty,min=0"`
+	}
+
+	AdminSetUserQuotaResp struct {
+		UserID uint  `json:"userId"`
+		Quota  int64 `json:"quota"` // 生效的配额
+		Used   int64 `json:"used"`
+	}
+)
+
+// 缩略图任务死信管理接口
+type (
+	AdminThumbnailDeadReq struct {
+		Before string `form:"before"` // 上一页返回的 nextBefore，不传从最新开始
+		Limit  int64  `form:"limit"`  // 默认20，最大100
+	}
+	AdminThumbnailDeadResp struct {
+		Pending    int64                    `json:"pending"`  // 等待处理或处理中的任务数
+		Retrying   int64                    `json:"retrying"` // 等待退避重试的任务数
+		Dead       int64                    `json:"dead"`     // 死信任务数
+		List       []AdminThumbnailDeadItem `json:"list"`
+		NextBefore string                   `json:"nextBefore"` // 为空表示没有更多
+	}
+
+	AdminThumbnailDeadItem struct {
+		ID          string `json:"id"`
+		MaterialID  uint   `json:"materialId"`
+		Bucket      string `json:"bucket"`
+		Key         string `json:"key"`
+		ContentType string `json:"contentType"`
+		Attempts    int    `json:"attempts"`
+		LastError   string `json:"lastError"`
+		FailedAt    string `json:"failedAt"`
+	}
+
+	// AdminThumbnailRequeueReq All 为 true 时重新投递全部死信任务
+	AdminThumbnailRequeueReq struct {
+		Ids []string `json:"ids"`
+		All bool     `json:"all"`
+	}
+	AdminThumbnailRequeueResp struct {
+		Requeued int `json:"requeued"`
+	}
+)
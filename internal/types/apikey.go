@@ -0,0 +1,44 @@
+package types
+
+// 创建API Key接口
+type (
+	APIKeyCreateReq struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes" binding:"required"`
+		ExpiresInDays int      `json:"expiresInDays"` // 0 表示永不过期
+	}
+
+	APIKeyCreateResp struct {
+		APIKeyInfo
+		Key string `json:"key"` // 明文密钥，仅在创建时返回一次
+	}
+)
+
+// API Key列表接口
+type (
+	APIKeyListReq  struct{}
+	APIKeyListResp struct {
+		Keys []APIKeyInfo `json:"keys"`
+	}
+
+	APIKeyInfo struct {
+		ID         uint     `json:"id"`
+		Name       string   `json:"name"`
+		Prefix     string   `json:"prefix"`
+		Scopes     []string `json:"scopes"`
+		ExpiresAt  string   `json:"expiresAt,omitempty"`
+		LastUsedAt string   `json:"lastUsedAt,omitempty"`
+		CreatedAt  string   `json:"createdAt"`
+	}
+)
+
+// 吊销API Key接口
+type (
+	APIKeyRevokeReq struct {
+		ID uint `json:"id" binding:"required"`
+	}
+
+	APIKeyRevokeResp struct {
+		Message string `json:"message"`
+	}
+)
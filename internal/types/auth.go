@@ -6,19 +6,72 @@ type (
 		Username string `json:"username" binding:"required"`
 		Password string `json:"password" binding:"required"`
 	}
-	
+
 	AuthLoginResp struct {
-		Token    string `json:"token"`
-		UserID   uint   `json:"userId"`
-		Username string `json:"username"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refreshToken"`
+		ExpiresIn    int64  `json:"expiresIn"` // 访问token有效期（秒）
+		UserID       uint   `json:"userId"`
+		Username     string `json:"username"`
+
+		// 已启用两步验证时不返回token，需携带 ChallengeToken 调用 /auth/login/2fa
+		TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+		ChallengeToken    string `json:"challengeToken,omitempty"`
+	}
+
+	// AuthLogin2FAReq 两步验证第二步，Code 可以是验证器App中的验证码或恢复码
+	AuthLogin2FAReq struct {
+		ChallengeToken string `json:"challengeToken" binding:"required"`
+		Code           string `json:"code" binding:"required"`
+	}
+)
+
+// 两步验证管理接口
+type (
+	TwoFactorEnrollReq  struct{}
+	TwoFactorEnrollResp struct {
+		Secret     string `json:"secret"`
+		OtpauthURI string `json:"otpauthUri"`
+	}
+
+	TwoFactorConfirmReq struct {
+		Code string `json:"code" binding:"required"`
+	}
+	TwoFactorConfirmResp struct {
+		RecoveryCodes []string `json:"recoveryCodes"` // 仅展示一次
+	}
+
+	TwoFactorDisableReq struct {
+		Code string `json:"code" binding:"required"`
+	}
+	TwoFactorDisableResp struct {
+		Message string `json:"message"`
+	}
+)
+
+// 刷新token接口
+type (
+	AuthRefreshReq struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+
+	AuthRefreshResp struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refreshToken"`
+		ExpiresIn    int64  `json:"expiresIn"` // 访问token有效期（秒）
 	}
 )
 
 // 登出接口
 type (
 	AuthLogoutReq struct {
+		RefreshToken string `json:"refreshToken"` // 可选，同时吊销对应的刷新token家族
 	}
-	
+
+	// AuthLogoutAllReq 退出所有设备
+	AuthLogoutAllReq struct {
+	}
+
 	AuthLogoutResp struct {
 		Message string `json:"message"`
 	}
@@ -29,11 +82,89 @@ type (
 	AuthRegisterReq struct {
 		Username string `json:"username" binding:"required"`
 		Password string `json:"password" binding:"required,min=6"`
+		Email    string `json:"email" binding:"omitempty,email"` // 填写后会发送验证邮件
 	}
-	
+
 	AuthRegisterResp struct {
 		UserID   uint   `json:"userId"`
 		Username string `json:"username"`
 		Message  string `json:"message"`
 	}
-)
\ No newline at end of file
+)
+
+// 邮箱验证、修改邮箱与找回密码接口
+type (
+	AuthVerifyEmailReq struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	AuthResendVerificationReq struct{}
+
+	AuthForgotPasswordReq struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	AuthResetPasswordReq struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"newPassword" binding:"required,min=6"`
+	}
+
+	// AuthChangeEmailReq 设置或修改邮箱；设置过密码的账号需提供当前密码
+	AuthChangeEmailReq struct {
+		Email    string `json:"email" binding:"required,email"`
+		Password string `json:"password"`
+	}
+
+	AuthConfirmEmailChangeReq struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	AuthMessageResp struct {
+		Message string `json:"message"`
+	}
+)
+
+// OIDC单点登录接口
+type (
+	AuthOIDCProvidersReq  struct{}
+	AuthOIDCProvidersResp struct {
+		Providers []string `json:"providers"`
+	}
+
+	// AuthOIDCStartReq 发起登录或关联，返回跳转到身份提供方的地址
+	AuthOIDCStartReq struct {
+		Provider string `json:"provider" binding:"required"`
+	}
+	AuthOIDCStartResp struct {
+		AuthURL string `json:"authUrl"`
+	}
+
+	// AuthOIDCCallbackReq 前端回调页将提供方带回的 code 和 state 原样提交；
+	// 登录流程提交到 /auth/oidc/callback，关联流程须带登录token提交到 /auth/oidc/link/callback
+	AuthOIDCCallbackReq struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+	}
+	AuthOIDCCallbackResp struct {
+		AuthLoginResp
+		Linked bool `json:"linked,omitempty"` // 关联流程完成时为true，不签发token
+	}
+)
+
+// 外部身份管理接口
+type (
+	AuthIdentityListReq  struct{}
+	AuthIdentityListResp struct {
+		List []AuthIdentityItem `json:"list"`
+	}
+	AuthIdentityItem struct {
+		ID        uint   `json:"id"`
+		Provider  string `json:"provider"`
+		Email     string `json:"email"`
+		CreatedAt string `json:"createdAt"`
+	}
+
+	AuthIdentityUnlinkReq struct {
+		ID uint `json:"id" binding:"required"`
+	}
+)
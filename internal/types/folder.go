@@ -0,0 +1,60 @@
+package types
+
+// FolderInfo 文件夹信息
+type FolderInfo struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	ParentID  *uint  `json:"parentId"` // 为空表示位于根目录
+	CreatedAt string `json:"createdAt"`
+}
+
+// 文件夹管理接口；ParentID/FolderID 不传或为0均表示根目录
+type (
+	FolderCreateReq struct {
+		Name     string `json:"name" binding:"required"`
+		ParentID *uint  `json:"parentId"`
+	}
+
+	FolderRenameReq struct {
+		ID   uint   `json:"id" binding:"required"`
+		Name string `json:"name" binding:"required"`
+	}
+
+	FolderMoveReq struct {
+		ID       uint  `json:"id" binding:"required"`
+		ParentID *uint `json:"parentId"`
+	}
+
+	FolderDeleteReq struct {
+		ID uint `json:"id" binding:"required"`
+	}
+	FolderDeleteResp struct {
+		Message string `json:"message"`
+	}
+
+	FolderListReq struct {
+		ParentID *uint `form:"parentId"`
+	}
+	FolderListResp struct {
+		Folders     []FolderInfo `json:"folders"`
+		Breadcrumbs []FolderInfo `json:"breadcrumbs"` // 从根目录到当前文件夹，根目录时为空
+	}
+
+	FolderBreadcrumbsReq struct {
+		ID uint `form:"id" binding:"required"`
+	}
+	FolderBreadcrumbsResp struct {
+		Breadcrumbs []FolderInfo `json:"breadcrumbs"`
+	}
+)
+
+// 移动素材接口
+type (
+	MaterialMoveReq struct {
+		Ids      []uint `json:"ids" binding:"required"`
+		FolderID *uint  `json:"folderId"`
+	}
+	MaterialMoveResp struct {
+		Message string `json:"message"`
+	}
+)
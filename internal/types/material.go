@@ -15,6 +15,8 @@ type (
 		Bucket          string `json:"bucket"`
 		Key             string `json:"key"`
 		Endpoint        string `json:"endpoint"`
+		ExpiresAt       string `json:"expiresAt"` // 凭证过期时间，过期后需重新获取
+		ExpiresIn       int64  `json:"expiresIn"` // 凭证剩余有效期（秒）
 	}
 )
 
@@ -27,39 +29,83 @@ type (
 		Bucket      string `json:"bucket" binding:"required"`
 		Key         string `json:"key" binding:"required"`
 		URL         string `json:"url" binding:"required"`
+		FolderID    *uint  `json:"folderId"` // 保存到的文件夹，不传为根目录
+		// 已有内容相同的文件时，引用已有的存储对象并删除本次上传的副本；
+		// 大文件的哈希由后台计算，复用在计算完成后执行。复用不减少配额占用，配额按每个素材的大小计算
+		ReuseExisting bool `json:"reuseExisting"`
 	}
 
 	MaterialSaveResp struct {
-		MaterialID uint `json:"materialId"`
+		MaterialID  uint   `json:"materialId"`
+		ContentHash string `json:"contentHash"` // 大文件由后台计算，此时为空
+		HashPending bool   `json:"hashPending"` // 哈希尚未算出，Duplicates 不完整，稍后通过 /material/:id/duplicates 查询
+		// 当前用户已有的内容相同的文件；ReusedFrom 不为0时表示新文件复用了该素材的存储对象
+		Duplicates []MaterialDuplicate `json:"duplicates"`
+		ReusedFrom uint                `json:"reusedFrom,omitempty"`
+	}
+
+	MaterialDuplicatesReq  struct{}
+	MaterialDuplicatesResp struct {
+		ContentHash string              `json:"contentHash"`
+		HashPending bool                `json:"hashPending"` // 后台尚未算出哈希，稍后再查
+		Duplicates  []MaterialDuplicate `json:"duplicates"`
+	}
+
+	MaterialDuplicate struct {
+		ID        uint   `json:"id"`
+		FileName  string `json:"fileName"`
+		FolderID  *uint  `json:"folderId"`
+		CreatedAt string `json:"createdAt"`
 	}
 )
 
 // 材料列表接口
 type (
-	MaterialListReq  struct{}
+	MaterialListReq struct {
+		FolderID *uint  `form:"folderId"` // 不传返回全部，0 表示根目录
+		TagIDs   []uint `form:"tagIds"`   // 按标签过滤，可重复传参
+		TagMode  string `form:"tagMode"`  // and: 同时带有全部标签；or（默认）: 带有任一标签
+
+		Family      string `form:"family"`      // 文件分类：image/video/audio/document/other
+		CreatedFrom string `form:"createdFrom"` // 创建时间范围，格式 2006-01-02 或 2006-01-02 15:04:05，只给日期时包含当天
+		CreatedTo   string `form:"createdTo"`
+		MinSize     *int64 `form:"minSize"` // 文件大小范围（字节），包含边界
+		MaxSize     *int64 `form:"maxSize"`
+
+		Sort   string `form:"sort"`   // 排序字段：createdAt（默认）/fileName/fileSize
+		Order  string `form:"order"`  // asc/desc（默认）
+		Cursor string `form:"cursor"` // 上一页返回的 nextCursor，不传为第一页
+		Limit  int    `form:"limit"`  // 每页数量，默认50，最大200
+	}
 	MaterialListResp struct {
-		Materials []MaterialInfo `json:"materials"`
+		Materials  []MaterialInfo `json:"materials"`
+		NextCursor string         `json:"nextCursor"` // 为空表示没有下一页
+		Total      int64          `json:"total"`      // 满足过滤条件的总数
 	}
 
 	// 搜索请求
 	MaterialSearchReq struct {
-		Q string `form:"q" binding:"required" json:"q"`
+		Q       string `form:"q" json:"q"`             // 文件名前缀，指定了标签时可为空
+		TagIDs  []uint `form:"tagIds" json:"tagIds"`   // 按标签过滤，可重复传参
+		TagMode string `form:"tagMode" json:"tagMode"` // and: 同时带有全部标签；or（默认）: 带有任一标签
 	}
 	MaterialSearchResp struct {
 		Materials []MaterialInfo `json:"materials"`
 	}
 
 	MaterialInfo struct {
-		ID              uint   `json:"id"`
-		FileName        string `json:"fileName"`
-		FileSize        int64  `json:"fileSize"`
-		ContentType     string `json:"contentType"`
-		URL             string `json:"url"`
-		CoverURL        string `json:"coverUrl"`
-		CoverPreviewURL string `json:"coverPreviewUrl"` // 预签名封面URL（用于页面展示）
-		DownloadURL     string `json:"downloadUrl"`     // 预签名下载URL
-		PreviewURL      string `json:"previewUrl"`      // 预签名预览URL
-		CreatedAt       string `json:"createdAt"`
+		ID              uint      `json:"id"`
+		FileName        string    `json:"fileName"`
+		FileSize        int64     `json:"fileSize"`
+		ContentType     string    `json:"contentType"`
+		URL             string    `json:"url"`
+		CoverURL        string    `json:"coverUrl"`
+		CoverPreviewURL string    `json:"coverPreviewUrl"` // 预签名封面URL（用于页面展示）
+		DownloadURL     string    `json:"downloadUrl"`     // 预签名下载URL
+		PreviewURL      string    `json:"previewUrl"`      // 预签名预览URL
+		CreatedAt       string    `json:"createdAt"`
+		FolderID        *uint     `json:"folderId"`
+		Tags            []TagInfo `json:"tags"`
 	}
 )
 
@@ -74,6 +120,46 @@ type (
 	}
 )
 
+// 打包下载接口，Ids 和 FolderID 二选一
+type MaterialZipReq struct {
+	Ids      []uint `form:"ids" json:"ids"`
+	FolderID uint   `form:"folderId" json:"folderId"` // 打包整个文件夹（含子文件夹）
+}
+
+// 回收站接口
+type (
+	MaterialTrashListReq  struct{}
+	MaterialTrashListResp struct {
+		List []MaterialTrashItem `json:"list"`
+	}
+
+	MaterialTrashItem struct {
+		ID              uint   `json:"id"`
+		FileName        string `json:"fileName"`
+		FileSize        int64  `json:"fileSize"`
+		ContentType     string `json:"contentType"`
+		CoverPreviewURL string `json:"coverPreviewUrl"`
+		CreatedAt       string `json:"createdAt"`
+		DeletedAt       string `json:"deletedAt"`
+		PurgeAt         string `json:"purgeAt"` // 到期后自动彻底删除
+	}
+
+	MaterialRestoreReq struct {
+		Ids []uint `json:"ids" binding:"required"`
+	}
+	MaterialRestoreResp struct {
+		Message string `json:"message"`
+	}
+
+	MaterialPurgeReq struct {
+		Ids []uint `json:"ids"`
+		All bool   `json:"all"` // 清空回收站
+	}
+	MaterialPurgeResp struct {
+		Message string `json:"message"`
+	}
+)
+
 // Publish material request
 type MaterialPublishReq struct {
 	MaterialID  uint   `json:"materialId"`
@@ -138,3 +224,82 @@ type (
 		FileName   string `json:"fileName"`
 	}
 )
+
+// 分片上传接口
+type (
+	MultipartInitiateReq struct {
+		FileName    string `json:"fileName" binding:"required"`
+		FileSize    int64  `json:"fileSize" binding:"required"`
+		ContentType string `json:"contentType"`
+		FolderID    *uint  `json:"folderId"` // 完成后保存到的文件夹，不传为根目录
+	}
+	MultipartInitiateResp struct {
+		UploadID  string `json:"uploadId"`
+		Bucket    string `json:"bucket"`
+		Key       string `json:"key"`
+		PartSize  int64  `json:"partSize"`  // 除最后一片外每片的大小
+		PartCount int    `json:"partCount"` // 分片总数，序号从1开始
+		ExpiresAt string `json:"expiresAt"` // 超过该时间未完成需重新发起
+	}
+
+	MultipartPresignReq struct {
+		UploadID    string `json:"uploadId" binding:"required"`
+		Key         string `json:"key" binding:"required"`
+		PartNumbers []int  `json:"partNumbers" binding:"required"`
+	}
+	MultipartPresignResp struct {
+		Parts     []MultipartPartURL `json:"parts"`
+		ExpiresAt string             `json:"expiresAt"` // 分片URL过期时间
+	}
+	MultipartPartURL struct {
+		PartNumber int    `json:"partNumber"`
+		URL        string `json:"url"` // 使用PUT上传分片内容，响应头中的ETag无需回传
+	}
+
+	MultipartPartsReq struct {
+		UploadID string `form:"uploadId" binding:"required"`
+		Key      string `form:"key" binding:"required"`
+	}
+	MultipartPartsResp struct {
+		Parts     []MultipartPart `json:"parts"`
+		PartSize  int64           `json:"partSize"`
+		PartCount int             `json:"partCount"`
+	}
+	MultipartPart struct {
+		PartNumber int    `json:"partNumber"`
+		ETag       string `json:"etag"`
+		Size       int64  `json:"size"`
+	}
+
+	MultipartCompleteReq struct {
+		UploadID      string `json:"uploadId" binding:"required"`
+		Key           string `json:"key" binding:"required"`
+		ReuseExisting bool   `json:"reuseExisting"` // 同 MaterialSaveReq.ReuseExisting
+	}
+
+	MultipartAbortReq struct {
+		UploadID string `json:"uploadId" binding:"required"`
+		Key      string `json:"key" binding:"required"`
+	}
+	MultipartAbortResp struct {
+		Message string `json:"message"`
+	}
+
+	MaterialProcessingReq  struct{}
+	MaterialProcessingResp struct {
+		MaterialID      uint   `json:"materialId"`
+		State           string `json:"state"`           // queued/processing/done/skipped/failed/unknown
+		Error           string `json:"error,omitempty"` // 最近一次失败的原因
+		Attempts        int    `json:"attempts"`
+		UpdatedAt       string `json:"updatedAt,omitempty"`
+		CoverPreviewURL string `json:"coverPreviewUrl,omitempty"` // 封面已生成时返回
+	}
+
+	MaterialProcessingStreamReq struct{}
+
+	MaterialStreamTicketReq  struct{}
+	MaterialStreamTicketResp struct {
+		Ticket    string `json:"ticket"`    // 通过 access_token 参数连接推送流，只能使用一次
+		ExpiresIn int64  `json:"expiresIn"` // 有效期（秒）
+	}
+)
@@ -0,0 +1,25 @@
+package types
+
+// 登录会话列表接口
+type (
+	SessionListReq  struct{}
+	SessionListResp struct {
+		List []SessionItem `json:"list"`
+	}
+
+	SessionItem struct {
+		SessionID  string `json:"sessionId"`
+		UserAgent  string `json:"userAgent"`
+		IP         string `json:"ip"`
+		CreatedAt  string `json:"createdAt"`
+		LastSeenAt string `json:"lastSeenAt"`
+		Current    bool   `json:"current"` // 是否为发起本次请求的会话
+	}
+)
+
+// 结束会话接口
+type (
+	SessionRevokeReq struct {
+		SessionID string `json:"sessionId" binding:"required"`
+	}
+)
@@ -0,0 +1,95 @@
+package types
+
+// 分享链接管理接口
+type (
+	// ShareCreateReq MaterialID 和 FolderID 二选一
+	ShareCreateReq struct {
+		MaterialID    uint   `json:"materialId"`
+		FolderID      uint   `json:"folderId"`
+		Password      string `json:"password"`      // 为空表示无需密码
+		ExpiresIn     int64  `json:"expiresIn"`     // 有效期（秒），0表示永不过期
+		MaxDownloads  int    `json:"maxDownloads"`  // 最多签发下载地址的次数，0表示不限制；每个地址在有效期内可重复使用
+		AllowPreview  *bool  `json:"allowPreview"`  // 默认允许，提供封面缩略图和在线查看原文件
+		AllowDownload *bool  `json:"allowDownload"` // 默认允许
+	}
+
+	ShareListReq  struct{}
+	ShareListResp struct {
+		List []ShareLinkInfo `json:"list"`
+	}
+
+	ShareRevokeReq struct {
+		Ids []uint `json:"ids" binding:"required"`
+	}
+	ShareRevokeResp struct {
+		Message string `json:"message"`
+	}
+
+	ShareLinkInfo struct {
+		ID            uint   `json:"id"`
+		Slug          string `json:"slug"`
+		Path          string `json:"path"` // 访问路径 /s/<slug>
+		Type          string `json:"type"` // material 或 folder
+		TargetID      uint   `json:"targetId"`
+		TargetName    string `json:"targetName"` // 分享对象已删除时为空
+		HasPassword   bool   `json:"hasPassword"`
+		ExpiresAt     string `json:"expiresAt"` // 为空表示永不过期
+		Expired       bool   `json:"expired"`
+		MaxDownloads  int    `json:"maxDownloads"`
+		DownloadCount int    `json:"downloadCount"` // 已签发的下载地址数
+		ViewCount     int    `json:"viewCount"`     // 已签发的预览地址数
+		AllowPreview  bool   `json:"allowPreview"`
+		AllowDownload bool   `json:"allowDownload"`
+		CreatedAt     string `json:"createdAt"`
+	}
+)
+
+// 公开访问分享链接接口；密码通过请求头 X-Share-Password 或 POST 请求体传递，不接受查询参数，避免进入访问日志
+type (
+	ShareResolveReq struct {
+		Password string `json:"password" form:"-"`
+		FolderID uint   `json:"folderId" form:"folderId"` // 文件夹分享中浏览的子文件夹，不传为分享的文件夹本身
+	}
+	ShareResolveResp struct {
+		Type               string       `json:"type"` // material 或 folder
+		Name               string       `json:"name"`
+		ExpiresAt          string       `json:"expiresAt"`
+		AllowPreview       bool         `json:"allowPreview"`
+		AllowDownload      bool         `json:"allowDownload"`
+		RemainingDownloads int          `json:"remainingDownloads"` // -1表示不限制
+		Material           *ShareItem   `json:"material,omitempty"`
+		Folders            []FolderInfo `json:"folders,omitempty"`
+		Materials          []ShareItem  `json:"materials,omitempty"`
+		Breadcrumbs        []FolderInfo `json:"breadcrumbs,omitempty"` // 从分享的文件夹到当前浏览的子文件夹
+	}
+
+	ShareItem struct {
+		ID          uint   `json:"id"`
+		FileName    string `json:"fileName"`
+		FileSize    int64  `json:"fileSize"`
+		ContentType string `json:"contentType"`
+		CoverURL    string `json:"coverUrl"` // 封面缩略图，短时有效；不允许预览或没有封面时为空
+		CreatedAt   string `json:"createdAt"`
+	}
+
+	ShareDownloadReq struct {
+		Password   string `json:"password" form:"-"`
+		MaterialID uint   `json:"materialId" form:"materialId"` // 单个素材的分享可不传
+		Inline     bool   `json:"inline" form:"inline"`         // 返回在线查看的地址，同样计入下载次数
+	}
+	// ShareDownloadResp 每次请求计一次下载，返回的地址在 ExpiresIn 秒内可重复使用
+	ShareDownloadResp struct {
+		URL       string `json:"url"`
+		ExpiresIn int64  `json:"expiresIn"`
+	}
+
+	// SharePreviewReq 在线查看原文件，需分享允许预览，不要求允许下载
+	SharePreviewReq struct {
+		Password   string `json:"password" form:"-"`
+		MaterialID uint   `json:"materialId" form:"materialId"` // 单个素材的分享可不传
+	}
+	SharePreviewResp struct {
+		URL       string `json:"url"`       // inline 方式打开原文件，短时有效
+		ExpiresIn int64  `json:"expiresIn"` // 有效期（秒）
+	}
+)
@@ -0,0 +1,46 @@
+package types
+
+// TagInfo 标签信息
+type TagInfo struct {
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	Color         string `json:"color"`
+	MaterialCount int64  `json:"materialCount,omitempty"` // 仅标签列表返回
+}
+
+// 标签管理接口
+type (
+	TagListReq  struct{}
+	TagListResp struct {
+		Tags []TagInfo `json:"tags"`
+	}
+
+	TagCreateReq struct {
+		Name  string `json:"name" binding:"required"`
+		Color string `json:"color"`
+	}
+
+	TagUpdateReq struct {
+		ID    uint   `json:"id" binding:"required"`
+		Name  string `json:"name" binding:"required"`
+		Color string `json:"color"`
+	}
+
+	TagDeleteReq struct {
+		ID uint `json:"id" binding:"required"`
+	}
+	TagDeleteResp struct {
+		Message string `json:"message"`
+	}
+)
+
+// 批量添加/移除素材标签接口
+type (
+	MaterialTagsReq struct {
+		MaterialIDs []uint `json:"materialIds" binding:"required"`
+		TagIDs      []uint `json:"tagIds" binding:"required"`
+	}
+	MaterialTagsResp struct {
+		Message string `json:"message"`
+	}
+)
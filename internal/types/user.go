@@ -20,11 +20,31 @@ type (
 	UserProfileReq struct{}
 	// UserProfileResp 用户个人信息响应
 	UserProfileResp struct {
-		ID       uint   `json:"id"`
-		Username string `json:"username"`
-		Avatar   string `json:"avatar"`
-		Nickname string `json:"nickname"`
-		Gender   int    `json:"gender"`
+		ID            uint   `json:"id"`
+		Username      string `json:"username"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"emailVerified"`
+		Avatar        string `json:"avatar"`
+		Nickname      string `json:"nickname"`
+		Gender        int    `json:"gender"`
+	}
+)
+
+// 存储用量接口
+type (
+	UserStorageReq  struct{}
+	UserStorageResp struct {
+		Used      int64              `json:"used"`      // 已用字节数，含回收站
+		Quota     int64              `json:"quota"`     // 0 表示不限制
+		Available int64              `json:"available"` // 不限制时为 -1
+		Breakdown []StorageBreakdown `json:"breakdown"` // 按内容类型分类
+		Trash     StorageBreakdown   `json:"trash"`     // 回收站占用
+	}
+
+	StorageBreakdown struct {
+		Type  string `json:"type,omitempty"` // image/video/audio/document/other
+		Count int64  `json:"count"`
+		Bytes int64  `json:"bytes"`
 	}
 )
 
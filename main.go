@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"godir/internal/common/ginx"
 	"godir/internal/common/jwt"
 	"godir/internal/common/logger"
+	"godir/internal/common/redis"
 	"godir/internal/common/svc"
 	"godir/internal/handler"
 
@@ -18,6 +23,9 @@ import (
 
 var configFile = flag.String("c", "config/local.yml", "配置文件路径")
 
+// 等待进行中的HTTP请求结束的最长时间
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	flag.Parse()
 
@@ -37,18 +45,36 @@ func main() {
 	log.With(zap.String("config", *configFile), zap.Int64("port", serviceContext.Cfg.Server.Port)).Info("应用启动")
 
 	// 初始化JWT配置
-	if err := jwt.Init(serviceContext.Cfg.JWT.SecretKey, serviceContext.Cfg.JWT.TokenExp); err != nil {
+	if err := jwt.Init(serviceContext.Cfg.JWT); err != nil {
 		log.Error("JWT初始化失败", zap.String("error", err.Error()))
 		os.Exit(1)
 	}
 
+	// 引导首个管理员：还没有管理员时，配置中列出的账号在启动时提升为管理员
+	promoted, err := svc.BootstrapAdmins(serviceContext.DB, serviceContext.Cfg.Admin.UserIDs)
+	if err != nil {
+		log.Error("提升管理员失败", zap.String("error", err.Error()))
+		os.Exit(1)
+	}
+	if len(promoted) > 0 {
+		log.Info("已提升为管理员", zap.Strings("usernames", promoted))
+	}
+
 	engine := ginx.New(log)
 	handler.RegisterRouter(engine)
 
-	log.Info("服务器启动中", zap.String("address", fmt.Sprintf(":%d", serviceContext.Cfg.Server.Port)))
+	// 缩略图工作池单独管理生命周期，退出前等待进行中的任务完成
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	thumbnails := redis.StartThumbnailWorker(workerCtx)
+
+	addr := fmt.Sprintf(":%d", serviceContext.Cfg.Server.Port)
+	server := &http.Server{Addr: addr, Handler: engine}
+	server.RegisterOnShutdown(ginx.CloseStreams)
+
+	log.Info("服务器启动中", zap.String("address", addr))
 
 	go func() {
-		if err := engine.Run(fmt.Sprintf(":%d", serviceContext.Cfg.Server.Port)); err != nil {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error("服务器启动失败", zap.String("error", err.Error()))
 			os.Exit(2)
 		}
@@ -60,6 +86,28 @@ func main() {
 	<-quit
 
 	log.Info("收到停止信号，正在关闭服务器...")
+
+	// 先停止接收新请求并等待进行中的请求结束
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error("关闭HTTP服务失败", zap.String("error", err.Error()))
+	}
+	cancel()
+
+	// 再停止领取缩略图任务，等待进行中的任务完成；任务自身有超时，超出等待时间的任务未确认，重启后会被重新认领
+	stopWorkers()
+	drained := make(chan struct{})
+	go func() {
+		thumbnails.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		log.Info("缩略图任务已全部处理完")
+	case <-time.After(redis.ThumbnailTimeout() + 10*time.Second):
+		log.Warn("等待缩略图任务超时，未完成的任务将在重启后重新处理")
+	}
+
 	log.Sync() // 确保所有日志都被写入
 	log.Info("server stopped")
 }